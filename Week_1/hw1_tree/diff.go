@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	changeAdded    = "+"
+	changeRemoved  = "-"
+	changeModified = "~"
+)
+
+// dirTreeDiff обходит два каталога теми же опциями и печатает одно общее
+// дерево: + появилось в newPath, - пропало, ~ изменился размер
+func dirTreeDiff(output io.Writer, oldPath, newPath string, opts treeOptions) error {
+	oldRoot, err := scanTree(oldPath, opts)
+	if err != nil {
+		return err
+	}
+	newRoot, err := scanTree(newPath, opts)
+	if err != nil {
+		return err
+	}
+	if oldRoot == nil || newRoot == nil {
+		return fmt.Errorf("diff needs two directories: %s, %s", oldPath, newPath)
+	}
+
+	if err := printTree(output, diffTrees(oldRoot, newRoot, opts), opts); err != nil {
+		return err
+	}
+
+	return errors.Join(append(oldRoot.errs, newRoot.errs...)...)
+}
+
+func diffTrees(oldDir, newDir *node, opts treeOptions) *node {
+	merged := &node{
+		Name:    newDir.Name,
+		Type:    newDir.Type,
+		Size:    newDir.Size,
+		Target:  newDir.Target,
+		dirLink: newDir.dirLink,
+		modTime: newDir.modTime,
+	}
+
+	oldChildren := map[string]*node{}
+	newChildren := map[string]*node{}
+	var names []string
+	for _, child := range oldDir.Children {
+		oldChildren[child.Name] = child
+		names = append(names, child.Name)
+	}
+	for _, child := range newDir.Children {
+		newChildren[child.Name] = child
+		if _, ok := oldChildren[child.Name]; !ok {
+			names = append(names, child.Name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		oldChild, newChild := oldChildren[name], newChildren[name]
+
+		switch {
+		case newChild == nil:
+			merged.Children = append(merged.Children, markTree(oldChild, changeRemoved))
+		case oldChild == nil:
+			merged.Children = append(merged.Children, markTree(newChild, changeAdded))
+		case oldChild.isDir() != newChild.isDir():
+			merged.Children = append(merged.Children,
+				markTree(oldChild, changeRemoved), markTree(newChild, changeAdded))
+		case newChild.isDir():
+			child := diffTrees(oldChild, newChild, opts)
+			if opts.du && oldChild.Size != newChild.Size {
+				child.Change = changeModified
+				child.OldSize = oldChild.Size
+			}
+			merged.Children = append(merged.Children, child)
+		default:
+			if oldChild.Size != newChild.Size || oldChild.Target != newChild.Target {
+				newChild.Change = changeModified
+				newChild.OldSize = oldChild.Size
+			}
+			merged.Children = append(merged.Children, newChild)
+		}
+	}
+	sortNodes(merged.Children, opts)
+
+	return merged
+}
+
+// markTree помечает поддерево целиком, чтобы каждая строка вывода несла знак
+func markTree(n *node, change string) *node {
+	n.Change = change
+	for _, child := range n.Children {
+		markTree(child, change)
+	}
+	return n
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+const testDiffResult = `├───+ added
+│	└───+ new.txt (3b)
+├───- gone.txt (4b)
+├───same
+│	├───~ grown.txt (2b -> 5b)
+│	└───kept.txt (4b)
+├───- swap
+│	└───- inner.txt (empty)
+└───+ swap (4b)
+`
+
+func TestTreeDiff(t *testing.T) {
+	oldRoot, newRoot := t.TempDir(), t.TempDir()
+	writeFiles(t, oldRoot, map[string]string{
+		"gone.txt":       "gone",
+		"same/kept.txt":  "kept",
+		"same/grown.txt": "ab",
+		"swap/inner.txt": "",
+	})
+	writeFiles(t, newRoot, map[string]string{
+		"added/new.txt":  "new",
+		"same/kept.txt":  "kept",
+		"same/grown.txt": "abcde",
+		"swap":           "file",
+	})
+
+	out := new(bytes.Buffer)
+	err := dirTreeDiff(out, oldRoot, newRoot, treeOptions{printFiles: true, diff: true})
+	if err != nil {
+		t.Errorf("test for diff Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testDiffResult {
+		t.Errorf("test for diff Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testDiffResult)
+	}
+}
+
+func TestTreeDiffSame(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := dirTreeDiff(out, "testdata", "testdata", treeOptions{printFiles: true, diff: true})
+	if err != nil {
+		t.Errorf("test for diff Failed - error: %v", err)
+	}
+
+	expected := new(bytes.Buffer)
+	dirTree(expected, "testdata", true)
+	if out.String() != expected.String() {
+		t.Errorf("test for diff Failed - identical trees must print as a plain tree\nGot:\n%v\nExpected:\n%v", out, expected)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatXML  = "xml"
+)
+
+func printJSON(output io.Writer, root *node) error {
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(root)
+}
+
+func printXML(output io.Writer, root *node) error {
+	if _, err := io.WriteString(output, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(output)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(output, "\n")
+	return err
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const gitignoreName = ".gitignore"
+
+// раньше эти файлы были захардкожены в checkIgnoredFile, теперь это просто
+// правила по умолчанию
+var defaultIgnorePatterns = []string{
+	".DS_Store",
+	".gitignore",
+	".directory",
+	".vscode",
+}
+
+type ignoreRule struct {
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher хранит правила в порядке их появления: выигрывает последнее
+// подошедшее, поэтому правила из вложенных .gitignore перекрывают родительские
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// with возвращает новый matcher с правилами patterns, заданными относительно
+// каталога base; исходный matcher не меняется и остаётся у соседних веток
+func (m *ignoreMatcher) with(base string, patterns []string) *ignoreMatcher {
+	var rules []ignoreRule
+	for _, pattern := range patterns {
+		if rule, ok := parseIgnoreRule(base, pattern); ok {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return m
+	}
+
+	next := &ignoreMatcher{}
+	if m != nil {
+		next.rules = append(next.rules, m.rules...)
+	}
+	next.rules = append(next.rules, rules...)
+	return next
+}
+
+// match проверяет путь rel (относительно корня обхода, через "/")
+func (m *ignoreMatcher) match(rel string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		name := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			name = rel[len(rule.base)+1:]
+		}
+
+		if rule.re.MatchString(name) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func parseIgnoreRule(base, pattern string) (ignoreRule, bool) {
+	rule := ignoreRule{base: base}
+
+	pattern = strings.TrimRight(pattern, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return rule, false
+	}
+
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\!`) || strings.HasPrefix(pattern, `\#`) {
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return rule, false
+	}
+
+	// слеш в начале или в середине привязывает шаблон к каталогу base,
+	// иначе шаблон совпадает с именем на любом уровне вложенности
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if strings.HasPrefix(pattern, "**/") {
+		anchored = false
+		pattern = pattern[3:]
+	}
+
+	expr := globToRegexp(pattern)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+func globToRegexp(pattern string) string {
+	var expr strings.Builder
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "/**/"):
+			expr.WriteString("/(?:.*/)?")
+			i += 3
+		case strings.HasPrefix(pattern[i:], "/**") && i+3 == len(pattern):
+			expr.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	return expr.String()
+}
+
+// newIgnoreMatcher собирает правила корня обхода: умолчания, -ignore-file и -ignore
+func newIgnoreMatcher(opts treeOptions) (*ignoreMatcher, error) {
+	ignore := (*ignoreMatcher)(nil).with("", defaultIgnorePatterns)
+
+	if opts.ignoreFile != "" {
+		patterns, err := readIgnoreFile(opts.ignoreFile)
+		if err != nil {
+			return nil, err
+		}
+		ignore = ignore.with("", patterns)
+	}
+
+	return ignore.with("", opts.ignore), nil
+}
+
+func readIgnorePatterns(r io.Reader) ([]string, error) {
+	var patterns []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+
+	return patterns, scanner.Err()
+}
+
+func readIgnoreFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readIgnorePatterns(file)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatch(t *testing.T) {
+	cases := []struct {
+		patterns []string
+		rel      string
+		isDir    bool
+		ignored  bool
+	}{
+		{[]string{"*.log"}, "a.log", false, true},
+		{[]string{"*.log"}, "dir/a.log", false, true},
+		{[]string{"*.log", "!keep.log"}, "dir/keep.log", false, false},
+		{[]string{"build/"}, "build", false, false},
+		{[]string{"build/"}, "src/build", true, true},
+		{[]string{"/build"}, "src/build", true, false},
+		{[]string{"/build"}, "build", true, true},
+		{[]string{"doc/*.txt"}, "doc/a.txt", false, true},
+		{[]string{"doc/*.txt"}, "doc/sub/a.txt", false, false},
+		{[]string{"doc/**/*.txt"}, "doc/sub/deep/a.txt", false, true},
+		{[]string{"**/cache"}, "a/b/cache", true, true},
+		{[]string{"logs/**"}, "logs/x/y", false, true},
+		{[]string{"file[0-9].go"}, "file7.go", false, true},
+		{[]string{"file[!0-9].go"}, "file7.go", false, false},
+		{[]string{"# comment", ""}, "# comment", false, false},
+		{[]string{`\#hash`}, "#hash", false, true},
+	}
+
+	for _, c := range cases {
+		ignore := (*ignoreMatcher)(nil).with("", c.patterns)
+		if got := ignore.match(c.rel, c.isDir); got != c.ignored {
+			t.Errorf("patterns %q, path %q: got %v, expected %v", c.patterns, c.rel, got, c.ignored)
+		}
+	}
+}
+
+func TestIgnoreMatchNested(t *testing.T) {
+	ignore := (*ignoreMatcher)(nil).with("", []string{"*.tmp"})
+	nested := ignore.with("sub", []string{"!keep.tmp", "/local"})
+
+	if !nested.match("other.tmp", false) {
+		t.Errorf("root rule must apply")
+	}
+	if nested.match("sub/keep.tmp", false) {
+		t.Errorf("nested negation must override root rule")
+	}
+	if !nested.match("sub/local", false) || nested.match("local", false) {
+		t.Errorf("nested anchored rule must apply only inside its directory")
+	}
+	if ignore.match("sub/local", false) {
+		t.Errorf("parent matcher must stay unchanged")
+	}
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const testGitignoreResult = `├───keep.log (4b)
+├───main.go (empty)
+└───sub
+	├───debug.log (3b)
+	└───util.go (empty)
+`
+
+func TestTreeGitignore(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":     "*.log\n!keep.log\nbuild/\n",
+		"keep.log":       "keep",
+		"drop.log":       "drop",
+		"main.go":        "",
+		"build/out.bin":  "bin",
+		"sub/.gitignore": "!debug.log\n*.tmp\n",
+		"sub/debug.log":  "dbg",
+		"sub/trace.log":  "trc",
+		"sub/util.go":    "",
+		"sub/x.tmp":      "",
+	})
+
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, root, treeOptions{printFiles: true, gitignore: true})
+	if err != nil {
+		t.Errorf("test for gitignore Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testGitignoreResult {
+		t.Errorf("test for gitignore Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testGitignoreResult)
+	}
+}
+
+const testIgnoreResult = `├───project
+│	└───file.txt (19b)
+└───zzfile.txt (empty)
+`
+
+func TestTreeIgnorePatterns(t *testing.T) {
+	ignoreFile := filepath.Join(t.TempDir(), "ignore")
+	writeFiles(t, filepath.Dir(ignoreFile), map[string]string{"ignore": "static/\nzline\n"})
+
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, "testdata", treeOptions{
+		printFiles: true,
+		ignore:     []string{"*.png"},
+		ignoreFile: ignoreFile,
+	})
+	if err != nil {
+		t.Errorf("test for ignore Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testIgnoreResult {
+		t.Errorf("test for ignore Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testIgnoreResult)
+	}
+}
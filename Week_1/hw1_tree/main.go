@@ -1,11 +1,17 @@
 package main
 
 import (
+	"encoding/xml"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 type byFilename []os.FileInfo
@@ -26,7 +32,7 @@ func filterDirs(fileList []os.FileInfo) []os.FileInfo {
 	var filteredList []os.FileInfo
 
 	for _, file := range fileList {
-		if file.IsDir() {
+		if file.IsDir() || isSymlink(file) {
 			filteredList = append(filteredList, file)
 		}
 	}
@@ -34,95 +40,451 @@ func filterDirs(fileList []os.FileInfo) []os.FileInfo {
 	return filteredList
 }
 
-var ignoredFiles = []string{
-	".DS_Store",
-	".gitignore",
-	".directory",
-	".vscode",
+const (
+	nodeDir  = "dir"
+	nodeFile = "file"
+	nodeLink = "symlink"
+)
+
+type node struct {
+	XMLName  xml.Name `json:"-" xml:"node"`
+	Name     string   `json:"name" xml:"name,attr"`
+	Type     string   `json:"type" xml:"type,attr"`
+	Size     int64    `json:"size" xml:"size,attr"`
+	Target   string   `json:"target,omitempty" xml:"target,attr,omitempty"`
+	Broken   bool     `json:"broken,omitempty" xml:"broken,attr,omitempty"`
+	Loop     bool     `json:"loop,omitempty" xml:"loop,attr,omitempty"`
+	Change   string   `json:"change,omitempty" xml:"change,attr,omitempty"`
+	OldSize  int64    `json:"old_size,omitempty" xml:"old_size,attr,omitempty"`
+	Err      string   `json:"error,omitempty" xml:"error,attr,omitempty"`
+	Children []*node  `json:"children,omitempty" xml:"node"`
+
+	// ошибки этого каталога и всех вложенных, в порядке вывода
+	errs []error
+	// ссылка указывает на каталог
+	dirLink bool
+	// внутри есть файл, подходящий под -P
+	matched bool
+	modTime time.Time
 }
 
-func checkIgnoredFile(file string) bool {
-	for _, i := range ignoredFiles {
-		if i == file {
-			return true
-		}
+func (n *node) isDir() bool {
+	return n.Type == nodeDir || n.dirLink
+}
+
+type treeOptions struct {
+	printFiles bool
+	format     string
+	strict     bool
+	ignore     []string
+	ignoreFile string
+	gitignore  bool
+	maxDepth   int
+	du         bool
+	human      bool
+	summary    bool
+	jobs       int
+	follow     bool
+	diff       bool
+	sortBy     string
+	reverse    bool
+	dirsFirst  bool
+	pattern    string
+}
+
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+var openDir = func(path string) (*os.File, error) {
+	return os.Open(path)
+}
+
+// errorReason отрезает от ошибки путь: он и так виден в дереве
+func errorReason(err error) string {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err.Error()
+	}
+	return err.Error()
+}
+
+type walker struct {
+	opts treeOptions
+	// sem ограничивает число горутин, читающих каталоги параллельно с текущей
+	sem chan struct{}
+}
+
+func newWalker(opts treeOptions) *walker {
+	w := &walker{opts: opts}
+	if opts.jobs > 1 {
+		w.sem = make(chan struct{}, opts.jobs-1)
+	}
+	return w
+}
+
+// fail помечает узел ошибкой и запоминает её; в режиме strict ошибка
+// возвращается наверх и прерывает обход
+func (w *walker) fail(n *node, err error) error {
+	if w.opts.strict {
+		return err
 	}
-	return false
+	n.Err = errorReason(err)
+	n.errs = append(n.errs, err)
+	return nil
+}
+
+// tryAcquire не блокируется: если свободного воркера нет, каталог читается
+// в текущей горутине, поэтому рекурсия не может упереться в собственный лимит
+func (w *walker) tryAcquire() bool {
+	select {
+	case w.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *walker) release() {
+	<-w.sem
 }
 
-func printDir(output io.Writer, path string, openedDirs map[int]bool, depth int, printFiles bool) {
-	file, err := os.Open(path)
-	defer file.Close()
+func (w *walker) scanDir(path, rel string, depth int, ignore *ignoreMatcher, parents *dirChain) (*node, error) {
+	dir := &node{Name: path, Type: nodeDir}
+	if rel != "" {
+		dir.Name = filepath.Base(rel)
+	}
+
+	// глубже -L не спускаемся; для -du обход всё же нужен, но детей отбросим
+	tooDeep := w.opts.maxDepth > 0 && depth >= w.opts.maxDepth
+	if tooDeep && !w.opts.du {
+		return dir, nil
+	}
+
+	if w.opts.gitignore {
+		patterns, err := readIgnoreFile(path + "/" + gitignoreName)
+		if err != nil && !os.IsNotExist(err) {
+			if err := w.fail(dir, err); err != nil {
+				return nil, err
+			}
+		}
+		ignore = ignore.with(rel, patterns)
+	}
+
+	file, err := openDir(path)
 	if err != nil {
-		log.Fatal(err)
+		return dir, w.fail(dir, err)
 	}
 
-	fileInfo, _ := file.Readdir(-1)
-	if !printFiles {
+	fileInfo, err := file.Readdir(-1)
+	file.Close()
+	if err != nil {
+		if err := w.fail(dir, err); err != nil {
+			return nil, err
+		}
+	}
+	if !w.opts.printFiles && !w.opts.du && w.opts.pattern == "" {
 		fileInfo = filterDirs(fileInfo)
 	}
-	openedDirs[depth] = true
 	sort.Sort(byFilename(fileInfo))
 
+	// дети складываются по индексу, поэтому порядок вывода не зависит
+	// от того, какой подкаталог прочитался первым
+	children := make([]*node, len(fileInfo))
+	childErrs := make([]error, len(fileInfo))
+	wg := &sync.WaitGroup{}
+
 	for i, file := range fileInfo {
 		fileName := file.Name()
-		if checkIgnoredFile(fileName) {
+		fileRel := fileName
+		if rel != "" {
+			fileRel = rel + "/" + fileName
+		}
+		if ignore.match(fileRel, file.IsDir()) {
+			continue
+		}
+
+		nextPath := fmt.Sprintf("%s/%s", path, fileName)
+		if isSymlink(file) {
+			children[i], childErrs[i] = w.scanLink(nextPath, fileRel, depth, ignore, parents)
+			continue
+		}
+
+		if !file.IsDir() {
+			children[i] = &node{Name: fileName, Type: nodeFile, Size: file.Size()}
+			continue
+		}
+
+		next := &dirChain{info: file, parent: parents}
+		if !w.tryAcquire() {
+			children[i], childErrs[i] = w.scanDir(nextPath, fileRel, depth+1, ignore, next)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer w.release()
+			children[i], childErrs[i] = w.scanDir(nextPath, fileRel, depth+1, ignore, next)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, child := range children {
+		if childErrs[i] != nil {
+			return nil, childErrs[i]
+		}
+		if child == nil {
 			continue
 		}
 
-		isLast := i+1 == len(fileInfo)
+		child.modTime = fileInfo[i].ModTime()
+		dir.errs = append(dir.errs, child.errs...)
+
+		// с -P остаются только подходящие файлы и каталоги, которые к ним ведут
+		if w.opts.pattern != "" {
+			if child.isDir() && !child.matched && child.Err == "" {
+				continue
+			}
+			if !child.isDir() && !matchPattern(w.opts.pattern, child.Name) {
+				continue
+			}
+			dir.matched = true
+		}
+
+		if w.opts.du {
+			dir.Size += child.Size
+		}
+		if child.isDir() || w.opts.printFiles {
+			dir.Children = append(dir.Children, child)
+		}
+	}
+	sortNodes(dir.Children, w.opts)
+
+	if tooDeep {
+		dir.Children = nil
+	}
+
+	return dir, nil
+}
+
+func printDir(output io.Writer, dir *node, opts treeOptions, openedDirs map[int]bool, depth int) {
+	openedDirs[depth] = true
+
+	for i, file := range dir.Children {
+		isLast := i+1 == len(dir.Children)
 		openedDirs[depth] = !isLast
 		symbol := addTab(depth, isLast, openedDirs)
 
-		if file.IsDir() {
-			fmt.Fprintf(output, "%s%s\n", symbol, fileName)
-			nextPath := fmt.Sprintf("%s/%s", path, fileName)
+		name := file.Name
+		if file.Change != "" {
+			name = file.Change + " " + name
+		}
+		if file.Type == nodeLink {
+			name += " -> " + file.Target
+		}
 
-			printDir(output, nextPath, openedDirs, depth+1, printFiles)
-		} else if printFiles {
-			fileSize := printSize(file.Size())
+		switch {
+		case file.Err != "":
+			fmt.Fprintf(output, "%s%s (%s)\n", symbol, name, file.Err)
+		case file.Broken:
+			fmt.Fprintf(output, "%s%s (broken)\n", symbol, name)
+		case file.Loop:
+			fmt.Fprintf(output, "%s%s (recursive, not followed)\n", symbol, name)
+		case file.Change == changeModified:
+			fmt.Fprintf(output, "%s%s (%s -> %s)\n", symbol, name,
+				formatSize(file.OldSize, opts.human), formatSize(file.Size, opts.human))
+		case file.isDir() && !opts.du:
+			fmt.Fprintf(output, "%s%s\n", symbol, name)
+		default:
+			fmt.Fprintf(output, "%s%s%s\n", symbol, name, printSize(file.Size, opts.human))
+		}
 
-			fmt.Fprintf(output, "%s%s%s\n", symbol, fileName, fileSize)
+		if file.isDir() {
+			printDir(output, file, opts, openedDirs, depth+1)
 		}
 	}
 }
 
 func dirTree(output io.Writer, path string, printFiles bool) error {
-	openedDirs := map[int]bool{}
+	return dirTreeWithOptions(output, path, treeOptions{printFiles: printFiles})
+}
+
+func dirTreeWithOptions(output io.Writer, path string, opts treeOptions) error {
+	root, err := scanTree(path, opts)
+	if err != nil || root == nil {
+		return err
+	}
 
+	if err := printTree(output, root, opts); err != nil {
+		return err
+	}
+
+	// ошибки чтения не прерывают обход: они видны в дереве и собираются сюда
+	return errors.Join(root.errs...)
+}
+
+// scanTree возвращает nil без ошибки, если path - не каталог
+func scanTree(path string, opts treeOptions) (*node, error) {
 	stats, err := os.Stat(path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	if mode := stats.Mode(); mode.IsDir() {
-		printDir(output, path, openedDirs, 0, printFiles)
+	if mode := stats.Mode(); !mode.IsDir() {
+		return nil, nil
 	}
 
-	return nil
+	ignore, err := newIgnoreMatcher(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.sortBy {
+	case "", sortName, sortSize, sortMtime, sortExt:
+	default:
+		return nil, fmt.Errorf("unknown sort %q", opts.sortBy)
+	}
+	// формат проверяем до обхода, а не при печати: на большом дереве
+	// опечатка иначе всплыла бы только после полного сканирования
+	switch opts.format {
+	case "", formatText, formatJSON, formatXML:
+	default:
+		return nil, fmt.Errorf("unknown format %q", opts.format)
+	}
+	if err := checkPattern(opts.pattern); err != nil {
+		return nil, err
+	}
+
+	w := newWalker(opts)
+	return w.scanDir(path, "", 0, ignore, &dirChain{info: stats})
+}
+
+func printTree(output io.Writer, root *node, opts treeOptions) error {
+	var err error
+	switch opts.format {
+	case "", formatText:
+		printDir(output, root, opts, map[int]bool{}, 0)
+		if opts.summary {
+			printSummary(output, root, opts)
+		}
+	case formatJSON:
+		err = printJSON(output, root)
+	case formatXML:
+		err = printXML(output, root)
+	default:
+		err = fmt.Errorf("unknown format %q", opts.format)
+	}
+	return err
+}
+
+// treeFlags описывает флаги dirTree, значения пишутся в opts. Ошибки и
+// подсказку FlagSet не печатает - это делает main
+func treeFlags(opts *treeOptions) *flag.FlagSet {
+	flags := flag.NewFlagSet("tree", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.BoolVar(&opts.printFiles, "f", false, "print files")
+	flags.StringVar(&opts.format, "format", formatText, "output format: text, json or xml")
+	flags.BoolVar(&opts.strict, "strict", false, "abort on the first read error")
+	flags.Var((*stringList)(&opts.ignore), "ignore", "gitignore-style pattern to skip, can be repeated")
+	flags.StringVar(&opts.ignoreFile, "ignore-file", "", "file with gitignore-style patterns to skip")
+	flags.BoolVar(&opts.gitignore, "gitignore", false, "honor .gitignore files found during the walk")
+	flags.IntVar(&opts.maxDepth, "L", 0, "descend only `depth` levels deep, 0 means no limit")
+	flags.BoolVar(&opts.du, "du", false, "print cumulative size of each directory")
+	flags.BoolVar(&opts.human, "h", false, "print sizes in human readable units")
+	flags.BoolVar(&opts.summary, "summary", false, "print directories, files and bytes totals")
+	flags.IntVar(&opts.jobs, "j", 1, "number of directories read concurrently")
+	flags.BoolVar(&opts.follow, "follow-symlinks", false, "descend into symlinked directories")
+	flags.BoolVar(&opts.diff, "diff", false, "compare two directories: go run main.go old new -diff")
+	flags.StringVar(&opts.sortBy, "sort", sortName, "sort entries by name, size, mtime or ext")
+	flags.BoolVar(&opts.reverse, "r", false, "reverse the sort order")
+	flags.BoolVar(&opts.dirsFirst, "dirs-first", false, "list directories before files")
+	flags.StringVar(&opts.pattern, "P", "", "list only files matching `pattern`, alternatives are separated by |")
+	return flags
+}
+
+// usage печатает подсказку по запуску со списком флагов
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: go run main.go . [-f] [options]")
+	flags := treeFlags(&treeOptions{})
+	flags.SetOutput(w)
+	flags.PrintDefaults()
+}
+
+func parseArgs(args []string) ([]string, treeOptions, error) {
+	opts := treeOptions{}
+	flags := treeFlags(&opts)
+
+	// флаги могут идти как до, так и после пути: go run main.go . -f
+	var paths []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, opts, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		paths = append(paths, args[0])
+		args = args[1:]
+	}
+
+	expected := 1
+	if opts.diff {
+		expected = 2
+	}
+	if len(paths) != expected {
+		return nil, opts, fmt.Errorf("expected %d path(s), got %d", expected, len(paths))
+	}
+
+	return paths, opts, nil
 }
 
 func main() {
 	out := os.Stdout
 
-	if !(len(os.Args) == 2 || len(os.Args) == 3) {
-		panic("usage go run main.go . [-f]")
+	paths, opts, err := parseArgs(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		usage(os.Stderr)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		usage(os.Stderr)
+		os.Exit(2)
 	}
 
-	path := os.Args[1]
-	printFiles := len(os.Args) == 3 && os.Args[2] == "-f"
-	err := dirTree(out, path, printFiles)
+	if opts.diff {
+		err = dirTreeDiff(out, paths[0], paths[1], opts)
+	} else {
+		err = dirTreeWithOptions(out, paths[0], opts)
+	}
 	if err != nil {
-		panic(err.Error())
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
 
-func printSize(size int64) string {
+func printSize(size int64, human bool) string {
+	return fmt.Sprintf(" (%s)", formatSize(size, human))
+}
+
+func formatSize(size int64, human bool) string {
 	if size == 0 {
-		return " (empty)"
+		return "empty"
+	}
+	if human {
+		return humanSize(size)
 	}
 
-	return fmt.Sprintf(" (%vb)", size)
+	return fmt.Sprintf("%vb", size)
 }
 
 func addTab(depth int, isLast bool, openedDirs map[int]bool) string {
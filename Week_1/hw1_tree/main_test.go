@@ -2,6 +2,13 @@ package main
 
 import (
 	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -74,3 +81,217 @@ func TestTreeDir(t *testing.T) {
 		t.Errorf("test for OK Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testDirResult)
 	}
 }
+
+const testJSONResult = `{
+  "name": "testdata/project",
+  "type": "dir",
+  "size": 0,
+  "children": [
+    {
+      "name": "file.txt",
+      "type": "file",
+      "size": 19
+    },
+    {
+      "name": "gopher.png",
+      "type": "file",
+      "size": 70372
+    }
+  ]
+}
+`
+
+func TestTreeJSON(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, "testdata/project", treeOptions{printFiles: true, format: formatJSON})
+	if err != nil {
+		t.Errorf("test for JSON Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testJSONResult {
+		t.Errorf("test for JSON Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testJSONResult)
+	}
+}
+
+const testXMLResult = `<?xml version="1.0" encoding="UTF-8"?>
+<node name="testdata/zline" type="dir" size="0">
+  <node name="lorem" type="dir" size="0">
+    <node name="ipsum" type="dir" size="0"></node>
+  </node>
+</node>
+`
+
+func TestTreeXML(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, "testdata/zline", treeOptions{format: formatXML})
+	if err != nil {
+		t.Errorf("test for XML Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testXMLResult {
+		t.Errorf("test for XML Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testXMLResult)
+	}
+}
+
+func TestTreeUnknownFormat(t *testing.T) {
+	original := openDir
+	opened := 0
+	openDir = func(name string) (*os.File, error) {
+		opened++
+		return original(name)
+	}
+	defer func() { openDir = original }()
+
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, "testdata", treeOptions{format: "yaml"})
+	if err == nil || err.Error() != `unknown format "yaml"` {
+		t.Errorf("test for unknown format Failed - expected error, got %v", err)
+	}
+	if opened != 0 {
+		t.Errorf("test for unknown format Failed - tree was scanned, %d dirs opened", opened)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	paths, opts, err := parseArgs([]string{"testdata", "-f", "-format=json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "testdata" || !opts.printFiles || opts.format != formatJSON {
+		t.Errorf("wrong args parsed: %q %+v", paths, opts)
+	}
+
+	if _, _, err := parseArgs([]string{"old", "new"}); err == nil {
+		t.Errorf("expected error for two paths without -diff")
+	}
+	paths, opts, err = parseArgs([]string{"old", "-diff", "new"})
+	if err != nil || len(paths) != 2 || !opts.diff {
+		t.Errorf("wrong diff args parsed: %q %+v %v", paths, opts, err)
+	}
+}
+
+func TestParseArgsHelp(t *testing.T) {
+	if _, _, err := parseArgs([]string{"-help"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("expected flag.ErrHelp, got %v", err)
+	}
+	if _, _, err := parseArgs([]string{"testdata", "-nope"}); err == nil || errors.Is(err, flag.ErrHelp) {
+		t.Errorf("expected error for unknown flag, got %v", err)
+	}
+
+	out := new(bytes.Buffer)
+	usage(out)
+	if !strings.Contains(out.String(), "usage: go run main.go") || !strings.Contains(out.String(), "-format string") {
+		t.Errorf("unexpected usage:\n%v", out.String())
+	}
+}
+
+const testErrorResult = `├───project
+├───static (permission denied)
+└───zline
+	└───lorem
+		└───ipsum
+`
+
+// denyDir подменяет openDir так, что каталог path не открывается
+func denyDir(t *testing.T, path string) {
+	original := openDir
+	openDir = func(name string) (*os.File, error) {
+		if name == path {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+		}
+		return original(name)
+	}
+	t.Cleanup(func() { openDir = original })
+}
+
+func TestTreeReadError(t *testing.T) {
+	denyDir(t, "testdata/static")
+
+	out := new(bytes.Buffer)
+	err := dirTree(out, "testdata", false)
+	if !errors.Is(err, os.ErrPermission) {
+		t.Errorf("test for read error Failed - expected permission error, got %v", err)
+	}
+	result := out.String()
+	if result != testErrorResult {
+		t.Errorf("test for read error Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testErrorResult)
+	}
+}
+
+func TestTreeStrict(t *testing.T) {
+	denyDir(t, "testdata/static")
+
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, "testdata", treeOptions{strict: true})
+	if !errors.Is(err, os.ErrPermission) {
+		t.Errorf("test for strict Failed - expected permission error, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("test for strict Failed - expected no output, got:\n%v", out.String())
+	}
+}
+
+func TestTreeParallel(t *testing.T) {
+	denyDir(t, "testdata/static/css")
+
+	for _, opts := range []treeOptions{
+		{printFiles: true},
+		{printFiles: false},
+		{printFiles: true, du: true, maxDepth: 2},
+	} {
+		expected := new(bytes.Buffer)
+		expectedErr := dirTreeWithOptions(expected, "testdata", opts)
+
+		opts.jobs = 8
+		for i := 0; i < 20; i++ {
+			out := new(bytes.Buffer)
+			err := dirTreeWithOptions(out, "testdata", opts)
+			if out.String() != expected.String() {
+				t.Fatalf("test for parallel Failed - results not match\nGot:\n%v\nExpected:\n%v", out, expected)
+			}
+			if fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+				t.Fatalf("test for parallel Failed - errors not match\nGot: %v\nExpected: %v", err, expectedErr)
+			}
+		}
+	}
+}
+
+// benchTree создаёт дерево пошире, чем testdata, чтобы было что распараллеливать
+func benchTree(b *testing.B) string {
+	root := b.TempDir()
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			for k := 0; k < 8; k++ {
+				dir := filepath.Join(root, fmt.Sprint("d", i), fmt.Sprint("d", j), fmt.Sprint("d", k))
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					b.Fatal(err)
+				}
+				for f := 0; f < 4; f++ {
+					if err := os.WriteFile(filepath.Join(dir, fmt.Sprint("f", f)), []byte("data"), 0644); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		}
+	}
+	return root
+}
+
+func benchmarkTree(b *testing.B, jobs int) {
+	root := benchTree(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := dirTreeWithOptions(io.Discard, root, treeOptions{printFiles: true, jobs: jobs})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTreeSequential(b *testing.B) {
+	benchmarkTree(b, 1)
+}
+
+func BenchmarkTreeParallel(b *testing.B) {
+	benchmarkTree(b, 8)
+}
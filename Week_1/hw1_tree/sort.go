@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	sortName  = "name"
+	sortSize  = "size"
+	sortMtime = "mtime"
+	sortExt   = "ext"
+)
+
+// sortNodes ожидает детей, уже отсортированных по имени (byFilename):
+// сортировка стабильная, так что при равных ключах порядок остаётся алфавитным
+func sortNodes(nodes []*node, opts treeOptions) {
+	var less func(a, b *node) bool
+	switch opts.sortBy {
+	case sortSize:
+		less = func(a, b *node) bool { return a.Size < b.Size }
+	case sortMtime:
+		less = func(a, b *node) bool { return a.modTime.Before(b.modTime) }
+	case sortExt:
+		less = func(a, b *node) bool { return filepath.Ext(a.Name) < filepath.Ext(b.Name) }
+	default:
+		less = func(a, b *node) bool { return a.Name < b.Name }
+	}
+
+	if opts.reverse {
+		byKey := less
+		less = func(a, b *node) bool { return byKey(b, a) }
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if opts.dirsFirst && a.isDir() != b.isDir() {
+			return a.isDir()
+		}
+		return less(a, b)
+	})
+}
+
+// checkPattern проверяет все варианты -P заранее: matchPattern ошибки
+// шаблона молча считает несовпадением
+func checkPattern(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	for _, alternative := range strings.Split(pattern, "|") {
+		if _, err := filepath.Match(alternative, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %w", alternative, err)
+		}
+	}
+	return nil
+}
+
+// matchPattern работает как tree -P: шаблон проверяется по имени файла,
+// несколько шаблонов разделяются |
+func matchPattern(pattern, name string) bool {
+	for _, alternative := range strings.Split(pattern, "|") {
+		if ok, _ := filepath.Match(alternative, name); ok {
+			return true
+		}
+	}
+	return false
+}
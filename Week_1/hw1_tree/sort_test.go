@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testSortSizeResult = `├───project
+│	├───gopher.png (70372b)
+│	└───file.txt (19b)
+└───zline
+	├───lorem
+	│	├───ipsum
+	│	│	└───gopher.png (70372b)
+	│	├───gopher.png (70372b)
+	│	└───dolor.txt (empty)
+	└───empty.txt (empty)
+`
+
+func TestTreeSortSize(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, "testdata", treeOptions{
+		printFiles: true,
+		sortBy:     sortSize,
+		reverse:    true,
+		dirsFirst:  true,
+		ignore:     []string{"static", "zzfile.txt"},
+	})
+	if err != nil {
+		t.Errorf("test for sort Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testSortSizeResult {
+		t.Errorf("test for sort Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testSortSizeResult)
+	}
+}
+
+func TestSortNodesExt(t *testing.T) {
+	nodes := []*node{
+		{Name: "a.txt", Type: nodeFile},
+		{Name: "b.go", Type: nodeFile},
+		{Name: "c", Type: nodeDir},
+		{Name: "d.go", Type: nodeFile},
+	}
+	sortNodes(nodes, treeOptions{sortBy: sortExt})
+
+	expected := []string{"c", "b.go", "d.go", "a.txt"}
+	for i, n := range nodes {
+		if n.Name != expected[i] {
+			t.Fatalf("wrong order at %d: got %s, expected %s", i, n.Name, expected[i])
+		}
+	}
+}
+
+const testPatternResult = `└───static
+	├───css
+	│	└───body.css (28b)
+	└───js
+		└───site.js (10b)
+`
+
+func TestTreePattern(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, "testdata", treeOptions{printFiles: true, pattern: "*.css|*.js"})
+	if err != nil {
+		t.Errorf("test for pattern Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testPatternResult {
+		t.Errorf("test for pattern Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testPatternResult)
+	}
+}
+
+const testPatternDirResult = `└───static
+	├───css
+	└───js
+`
+
+func TestTreePatternDirs(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, "testdata", treeOptions{pattern: "*.css|*.js"})
+	if err != nil {
+		t.Errorf("test for pattern Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testPatternDirResult {
+		t.Errorf("test for pattern Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testPatternDirResult)
+	}
+}
+
+func TestTreeBadPattern(t *testing.T) {
+	for _, pattern := range []string{"[", "*.go|[a-"} {
+		out := new(bytes.Buffer)
+		err := dirTreeWithOptions(out, "testdata", treeOptions{printFiles: true, pattern: pattern})
+		if err == nil || !strings.Contains(err.Error(), "bad pattern") {
+			t.Errorf("test for bad pattern %q Failed - expected error, got %v", pattern, err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("test for bad pattern %q Failed - tree printed:\n%v", pattern, out.String())
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+const sizeUnits = "KMGTPE"
+
+// humanSize печатает размер как tree -h: 19b, 68.7K, 1.2M
+func humanSize(size int64) string {
+	if size < 1024 {
+		return fmt.Sprintf("%db", size)
+	}
+
+	value := float64(size)
+	unit := -1
+	for value >= 1024 && unit+1 < len(sizeUnits) {
+		value /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.1f%c", value, sizeUnits[unit])
+}
+
+type treeSummary struct {
+	dirs  int
+	files int
+	bytes int64
+}
+
+// countTree считает то, что попало в вывод; сам корень не учитывается
+func countTree(dir *node, summary *treeSummary) {
+	for _, child := range dir.Children {
+		if child.isDir() {
+			summary.dirs++
+			countTree(child, summary)
+			continue
+		}
+		summary.files++
+		summary.bytes += child.Size
+	}
+}
+
+func plural(count int, one, many string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, one)
+	}
+	return fmt.Sprintf("%d %s", count, many)
+}
+
+func printSummary(output io.Writer, root *node, opts treeOptions) {
+	summary := treeSummary{}
+	countTree(root, &summary)
+	// с -du корень уже знает размер всего дерева, включая обрезанное -L
+	if opts.du {
+		summary.bytes = root.Size
+	}
+
+	dirs := plural(summary.dirs, "directory", "directories")
+	if !opts.printFiles {
+		fmt.Fprintf(output, "\n%s\n", dirs)
+		return
+	}
+
+	bytes := plural(int(summary.bytes), "byte", "bytes")
+	if opts.human {
+		bytes = humanSize(summary.bytes)
+	}
+	fmt.Fprintf(output, "\n%s, %s, %s\n", dirs, plural(summary.files, "file", "files"), bytes)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHumanSize(t *testing.T) {
+	cases := map[int64]string{
+		19:      "19b",
+		1023:    "1023b",
+		1024:    "1.0K",
+		70372:   "68.7K",
+		5 << 20: "5.0M",
+		3 << 30: "3.0G",
+	}
+
+	for size, expected := range cases {
+		if got := humanSize(size); got != expected {
+			t.Errorf("humanSize(%d): got %q, expected %q", size, got, expected)
+		}
+	}
+}
+
+const testDepthResult = `├───project
+│	├───file.txt (19b)
+│	└───gopher.png (70372b)
+├───static
+│	├───a_lorem
+│	├───css
+│	├───empty.txt (empty)
+│	├───html
+│	├───js
+│	└───z_lorem
+├───zline
+│	├───empty.txt (empty)
+│	└───lorem
+└───zzfile.txt (empty)
+
+9 directories, 5 files, 70391 bytes
+`
+
+func TestTreeDepthSummary(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, "testdata", treeOptions{printFiles: true, maxDepth: 2, summary: true})
+	if err != nil {
+		t.Errorf("test for depth Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testDepthResult {
+		t.Errorf("test for depth Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testDepthResult)
+	}
+}
+
+const testDuResult = `├───project (68.7K)
+├───static (275.0K)
+│	├───a_lorem (137.4K)
+│	├───css (28b)
+│	├───html (57b)
+│	├───js (10b)
+│	└───z_lorem (137.4K)
+└───zline (137.4K)
+	└───lorem (137.4K)
+
+9 directories
+`
+
+func TestTreeDu(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, "testdata", treeOptions{maxDepth: 2, du: true, human: true, summary: true})
+	if err != nil {
+		t.Errorf("test for du Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testDuResult {
+		t.Errorf("test for du Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testDuResult)
+	}
+}
+
+const testDuDepthResult = `├───project (68.7K)
+├───static (275.0K)
+├───zline (137.4K)
+└───zzfile.txt (empty)
+
+3 directories, 1 file, 481.2K
+`
+
+func TestTreeDuDepthSummary(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, "testdata", treeOptions{printFiles: true, maxDepth: 1, du: true, human: true, summary: true})
+	if err != nil {
+		t.Errorf("test for du with depth Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testDuDepthResult {
+		t.Errorf("test for du with depth Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testDuDepthResult)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func isSymlink(file os.FileInfo) bool {
+	return file.Mode()&os.ModeSymlink != 0
+}
+
+// dirChain - каталоги от корня до текущего; по ним ловим циклы из ссылок.
+// os.SameFile сравнивает устройство и inode, так что не важно, каким путём
+// мы пришли в каталог
+type dirChain struct {
+	info   os.FileInfo
+	parent *dirChain
+}
+
+func (chain *dirChain) contains(info os.FileInfo) bool {
+	for ; chain != nil; chain = chain.parent {
+		if os.SameFile(chain.info, info) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *walker) scanLink(path, rel string, depth int, ignore *ignoreMatcher, parents *dirChain) (*node, error) {
+	link := &node{Name: filepath.Base(rel), Type: nodeLink}
+
+	target, err := os.Readlink(path)
+	if err != nil {
+		return link, w.fail(link, err)
+	}
+	link.Target = target
+
+	info, err := os.Stat(path)
+	if err != nil {
+		link.Broken = true
+		return link, nil
+	}
+
+	if !info.IsDir() {
+		link.Size = info.Size()
+		return link, nil
+	}
+
+	link.dirLink = true
+	if !w.opts.follow {
+		return link, nil
+	}
+	if parents.contains(info) {
+		link.Loop = true
+		return link, nil
+	}
+
+	dir, err := w.scanDir(path, rel, depth+1, ignore, &dirChain{info: info, parent: parents})
+	if err != nil {
+		return nil, err
+	}
+
+	link.Size = dir.Size
+	link.Err = dir.Err
+	link.Children = dir.Children
+	link.errs = dir.errs
+	return link, nil
+}
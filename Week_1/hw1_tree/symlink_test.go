@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// symlinkFixture собирает дерево со ссылками во временном каталоге:
+// класть ссылки (тем более циклические) прямо в testdata нельзя - их
+// начнут обходить TestTreeFull и TestTreeDir
+func symlinkFixture(t *testing.T) string {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a/file.txt": "hello",
+	})
+
+	links := map[string]string{
+		"a/loop": "..",
+		"b":      "a",
+		"broken": "missing",
+		"f":      "a/file.txt",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symlinks are not supported: %v", err)
+		}
+	}
+
+	return root
+}
+
+const testSymlinkResult = `├───a
+│	├───file.txt (5b)
+│	└───loop -> ..
+├───b -> a
+├───broken -> missing (broken)
+└───f -> a/file.txt (5b)
+`
+
+func TestTreeSymlinks(t *testing.T) {
+	root := symlinkFixture(t)
+
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, root, treeOptions{printFiles: true})
+	if err != nil {
+		t.Errorf("test for symlinks Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testSymlinkResult {
+		t.Errorf("test for symlinks Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testSymlinkResult)
+	}
+}
+
+const testFollowResult = `├───a
+│	├───file.txt (5b)
+│	└───loop -> .. (recursive, not followed)
+├───b -> a
+│	├───file.txt (5b)
+│	└───loop -> .. (recursive, not followed)
+├───broken -> missing (broken)
+└───f -> a/file.txt (5b)
+
+4 directories, 4 files, 15 bytes
+`
+
+func TestTreeFollowSymlinks(t *testing.T) {
+	root := symlinkFixture(t)
+
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, root, treeOptions{printFiles: true, follow: true, summary: true, jobs: 4})
+	if err != nil {
+		t.Errorf("test for follow Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testFollowResult {
+		t.Errorf("test for follow Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testFollowResult)
+	}
+}
+
+const testFollowDirResult = `├───a
+│	└───loop -> .. (recursive, not followed)
+└───b -> a
+	└───loop -> .. (recursive, not followed)
+`
+
+func TestTreeFollowSymlinksDirs(t *testing.T) {
+	root := symlinkFixture(t)
+
+	out := new(bytes.Buffer)
+	err := dirTreeWithOptions(out, root, treeOptions{follow: true})
+	if err != nil {
+		t.Errorf("test for follow dirs Failed - error: %v", err)
+	}
+	result := out.String()
+	if result != testFollowDirResult {
+		t.Errorf("test for follow dirs Failed - results not match\nGot:\n%v\nExpected:\n%v", result, testFollowDirResult)
+	}
+}
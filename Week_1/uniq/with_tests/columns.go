@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// keySeparator склеивает выбранные колонки в один ключ; в самих
+// полях он практически не встречается
+const keySeparator = "\x00"
+
+func (opts uniqOptions) delimiter() string {
+	if opts.delim != "" {
+		return opts.delim
+	}
+	if opts.csv {
+		return ","
+	}
+	return "\t"
+}
+
+// fields режет запись на колонки; в режиме csv учитываются кавычки,
+// поэтому разделитель и перевод строки внутри "..." не разбивают поле
+func (opts uniqOptions) fields(record string) []string {
+	delim := opts.delimiter()
+	if !opts.csv {
+		return strings.Split(record, delim)
+	}
+
+	r := csv.NewReader(strings.NewReader(record))
+	r.Comma, _ = utf8.DecodeRuneInString(delim)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	fields, err := r.Read()
+	if err != nil {
+		return strings.Split(record, delim)
+	}
+	return fields
+}
+
+// columnsKey собирает ключ из колонок keyColumns (нумерация с 1)
+func (opts uniqOptions) columnsKey(record string) string {
+	fields := opts.fields(record)
+
+	key := make([]string, len(opts.keyColumns))
+	for i, column := range opts.keyColumns {
+		if column <= len(fields) {
+			key[i] = fields[column-1]
+		}
+	}
+	return strings.Join(key, keySeparator)
+}
+
+// scanner читает вход построчно, а в режиме csv - по записям: перевод строки
+// внутри кавычек запись не заканчивает
+func (opts uniqOptions) scanner(input io.Reader) *bufio.Scanner {
+	in := bufio.NewScanner(input)
+	if opts.csv {
+		in.Split(scanCSVRecords)
+	}
+	return in
+}
+
+func scanCSVRecords(data []byte, atEOF bool) (int, []byte, error) {
+	inQuotes := false
+	for i, c := range data {
+		switch c {
+		case '"':
+			inQuotes = !inQuotes
+		case '\n':
+			if !inQuotes {
+				return i + 1, bytes.TrimSuffix(data[:i], []byte("\r")), nil
+			}
+		}
+	}
+
+	if atEOF && len(data) > 0 {
+		return len(data), bytes.TrimSuffix(data, []byte("\r")), nil
+	}
+	return 0, nil, nil
+}
+
+// parseColumns разбирает значение -key: "2" или "1,3"
+func parseColumns(value string) ([]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var columns []int
+	for _, part := range strings.Split(value, ",") {
+		column, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || column < 1 {
+			return nil, fmt.Errorf("bad key column %q", part)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeyColumns(t *testing.T) {
+	cases := []struct {
+		name   string
+		opts   uniqOptions
+		input  string
+		result string
+	}{
+		{
+			name:   "tsv",
+			opts:   uniqOptions{keyColumns: []int{2}},
+			input:  "10:00\tlogin\tbob\n10:01\tlogin\talice\n10:02\tlogout\tbob\n",
+			result: "10:00\tlogin\tbob\n10:02\tlogout\tbob\n",
+		},
+		{
+			name:   "tsv ordered by timestamp",
+			opts:   uniqOptions{keyColumns: []int{2}},
+			input:  "2026-01-01\tzeta\tx\n2026-01-02\tzeta\ty\n2026-01-03\talpha\tz\n",
+			result: "2026-01-01\tzeta\tx\n2026-01-03\talpha\tz\n",
+		},
+		{
+			name:   "several columns",
+			opts:   uniqOptions{keyColumns: []int{1, 3}, delim: ";", count: true},
+			input:  "a;1;x\na;2;x\na;3;y\n",
+			result: "      2 a;1;x\n      1 a;3;y\n",
+		},
+		{
+			name:   "missing column",
+			opts:   uniqOptions{keyColumns: []int{3}},
+			input:  "a\tb\nc\td\n",
+			result: "a\tb\n",
+		},
+		{
+			name:   "csv quotes",
+			opts:   uniqOptions{keyColumns: []int{2}, csv: true},
+			input:  "1,\"Smith, John\",a\n2,\"Smith, John\",b\n3,\"Smith, Kate\",c\n",
+			result: "1,\"Smith, John\",a\n3,\"Smith, Kate\",c\n",
+		},
+		{
+			name:   "csv multiline field",
+			opts:   uniqOptions{keyColumns: []int{1}, csv: true, count: true},
+			input:  "k1,\"first\nline\"\nk1,other\nk2,\"a \"\"quoted\"\" one\"\n",
+			result: "      2 k1,\"first\nline\"\n      1 k2,\"a \"\"quoted\"\" one\"\n",
+		},
+		{
+			name:   "csv unsorted with spill",
+			opts:   uniqOptions{keyColumns: []int{1}, csv: true, unsorted: true, maxMemory: 1},
+			input:  "b,\"x\ny\"\na,1\nb,2\n",
+			result: "b,\"x\ny\"\na,1\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			opts := c.opts
+			opts.tempDir = t.TempDir()
+			err := uniqWithOptions(bytes.NewBufferString(c.input), out, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if out.String() != c.result {
+				t.Errorf("result not match\nGot:\n%q\nExpected:\n%q", out.String(), c.result)
+			}
+		})
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	columns, err := parseColumns("1, 3")
+	if err != nil || len(columns) != 2 || columns[0] != 1 || columns[1] != 3 {
+		t.Errorf("wrong columns: %v %v", columns, err)
+	}
+
+	for _, bad := range []string{"0", "a", "1,,2"} {
+		if _, err := parseColumns(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
@@ -1,31 +1,131 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+type uniqOptions struct {
+	count      bool
+	repeated   bool
+	unique     bool
+	ignoreCase bool
+	skipFields int
+	skipChars  int
+	unsorted   bool
+	// лимит памяти для -unsorted в байтах, 0 - без лимита
+	maxMemory int
+	tempDir   string
+	// колонки ключа для -key, нумерация с 1
+	keyColumns []int
+	delim      string
+	csv        bool
+}
+
+// key возвращает ту часть строки, по которой строки сравниваются:
+// с -key берутся только нужные колонки, потом пропускаются skipFields
+// полей и skipChars символов
+func (opts uniqOptions) key(line string) string {
+	if len(opts.keyColumns) > 0 {
+		line = opts.columnsKey(line)
+	}
+
+	for i := 0; i < opts.skipFields && line != ""; i++ {
+		line = strings.TrimLeftFunc(line, unicode.IsSpace)
+		end := strings.IndexFunc(line, unicode.IsSpace)
+		if end < 0 {
+			end = len(line)
+		}
+		line = line[end:]
+	}
+
+	for i := 0; i < opts.skipChars && line != ""; i++ {
+		_, size := utf8.DecodeRuneInString(line)
+		line = line[size:]
+	}
+
+	if opts.ignoreCase {
+		line = strings.ToLower(line)
+	}
+	return line
+}
+
+// print выводит первую строку группы из count одинаковых строк
+func (opts uniqOptions) print(output io.Writer, line string, count int) {
+	if count == 0 || opts.repeated && count == 1 || opts.unique && count > 1 {
+		return
+	}
+	if opts.count {
+		fmt.Fprintf(output, "%7d %s\n", count, line)
+		return
+	}
+	fmt.Fprintln(output, line)
+}
+
 func uniq(input io.Reader, output io.Writer) error {
-	in := bufio.NewScanner(input)
-	var prev string
+	return uniqWithOptions(input, output, uniqOptions{})
+}
+
+func uniqWithOptions(input io.Reader, output io.Writer, opts uniqOptions) error {
+	if opts.unsorted {
+		return uniqUnsorted(input, output, opts)
+	}
+
+	in := opts.scanner(input)
+	var prev, prevKey, prevOrder string
+	count := 0
 	for in.Scan() {
 		txt := in.Text()
-		if txt == prev {
+		key := opts.key(txt)
+		if count > 0 && key == prevKey {
+			count++
 			continue
 		}
-		if txt < prev {
+		// порядок проверяем по строке целиком, как и без опций: -f и -s
+		// задают только равенство строк. С -i вход сортирован без учёта
+		// регистра (sort -f), поэтому и сравниваем строки в нижнем регистре
+		order := txt
+		if opts.ignoreCase {
+			order = strings.ToLower(txt)
+		}
+		if order < prevOrder {
 			return fmt.Errorf("file not sorted")
 		}
-		prev = txt
-		fmt.Fprintln(output, txt)
+		opts.print(output, prev, count)
+		prev, prevKey, prevOrder, count = txt, key, order, 1
 	}
-	return nil
+	opts.print(output, prev, count)
+	return in.Err()
 }
 
 func main() {
-	err := uniq(os.Stdin, os.Stdout)
+	opts := uniqOptions{}
+	flag.BoolVar(&opts.count, "c", false, "prefix lines by the number of occurrences")
+	flag.BoolVar(&opts.repeated, "d", false, "only print duplicate lines, one for each group")
+	flag.BoolVar(&opts.unique, "u", false, "only print unique lines")
+	flag.BoolVar(&opts.ignoreCase, "i", false, "ignore differences in case when comparing")
+	flag.IntVar(&opts.skipFields, "f", 0, "avoid comparing the first `N` fields")
+	flag.IntVar(&opts.skipChars, "s", 0, "avoid comparing the first `N` characters")
+	flag.BoolVar(&opts.unsorted, "unsorted", false, "accept unsorted input, keeping the first occurrence of each line")
+	flag.IntVar(&opts.maxMemory, "max-mem", 0, "with -unsorted, spill to disk after about `N` bytes, 0 means no limit")
+	keyColumns := flag.String("key", "", "compare only these `columns`, e.g. 2 or 1,3")
+	flag.StringVar(&opts.delim, "delim", "", "column delimiter for -key, tab by default or comma with -csv")
+	flag.BoolVar(&opts.csv, "csv", false, "parse records as CSV, honoring quotes")
+	flag.Parse()
+
+	columns, err := parseColumns(*keyColumns)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	opts.keyColumns = columns
+
+	err = uniqWithOptions(os.Stdin, os.Stdout, opts)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -44,3 +44,112 @@ func TestFail(t *testing.T) {
 		t.Errorf("Test FAIL failed: expected error")
 	}
 }
+
+func TestOptions(t *testing.T) {
+	cases := []struct {
+		name   string
+		opts   uniqOptions
+		input  string
+		result string
+	}{
+		{
+			name:   "count",
+			opts:   uniqOptions{count: true},
+			input:  "a\na\nb\nc\nc\nc\n",
+			result: "      2 a\n      1 b\n      3 c\n",
+		},
+		{
+			name:   "repeated",
+			opts:   uniqOptions{repeated: true},
+			input:  "a\na\nb\nc\nc\nc\n",
+			result: "a\nc\n",
+		},
+		{
+			name:   "unique",
+			opts:   uniqOptions{unique: true},
+			input:  "a\na\nb\nc\nc\nc\n",
+			result: "b\n",
+		},
+		{
+			name:   "repeated and unique",
+			opts:   uniqOptions{repeated: true, unique: true},
+			input:  "a\na\nb\n",
+			result: "",
+		},
+		{
+			name:   "ignore case",
+			opts:   uniqOptions{ignoreCase: true, count: true},
+			input:  "a\nA\nb\nB\nb\n",
+			result: "      2 a\n      3 b\n",
+		},
+		{
+			name:   "ignore case sorted with sort -f",
+			opts:   uniqOptions{ignoreCase: true, count: true},
+			input:  "apple\nBanana\nbanana\nCherry\n",
+			result: "      1 apple\n      2 Banana\n      1 Cherry\n",
+		},
+		{
+			name:   "skip fields",
+			opts:   uniqOptions{skipFields: 1},
+			input:  "1 apple\n3 apple\n4 banana\n  9 banana\n",
+			result: "1 apple\n4 banana\n",
+		},
+		{
+			name:   "skip fields with keys going down",
+			opts:   uniqOptions{skipFields: 1},
+			input:  "a z\nb y\nc y\n",
+			result: "a z\nb y\n",
+		},
+		{
+			name:   "skip chars",
+			opts:   uniqOptions{skipChars: 2},
+			input:  "x-one\ny-one\nz-two\n",
+			result: "x-one\nz-two\n",
+		},
+		{
+			name:   "skip fields then chars",
+			opts:   uniqOptions{skipFields: 1, skipChars: 2},
+			input:  "10:00 #ab\n10:05 $ab\n10:07 #cd\n",
+			result: "10:00 #ab\n10:07 #cd\n",
+		},
+		{
+			name:   "skip more fields than present",
+			opts:   uniqOptions{skipFields: 5, count: true},
+			input:  "a b\nc d\n",
+			result: "      2 a b\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			err := uniqWithOptions(bytes.NewBufferString(c.input), out, c.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if out.String() != c.result {
+				t.Errorf("result not match\nGot:\n%q\nExpected:\n%q", out.String(), c.result)
+			}
+		})
+	}
+}
+
+func TestOptionsNotSorted(t *testing.T) {
+	cases := []struct {
+		name  string
+		opts  uniqOptions
+		input string
+	}{
+		{"plain", uniqOptions{}, "b\na\n"},
+		{"ignore case", uniqOptions{ignoreCase: true}, "b\nA\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := uniqWithOptions(bytes.NewBufferString(c.input), bytes.NewBuffer(nil), c.opts)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// примерная цена одной записи в памяти сверх самих строк
+const recordOverhead = 64
+
+// uniqRecord - группа одинаковых строк: первая встреченная строка,
+// её номер во входе и сколько раз встретился ключ
+type uniqRecord struct {
+	index int
+	count int
+	line  string
+	key   string
+}
+
+func (r uniqRecord) size() int {
+	return len(r.line) + len(r.key) + recordOverhead
+}
+
+// uniqUnsorted убирает дубликаты из неотсортированного входа, сохраняя порядок
+// первых вхождений. Пока группы помещаются в opts.maxMemory, всё считается
+// в памяти; иначе группы сбрасываются на диск отсортированными кусками,
+// которые сливаются сначала по ключу (склеить дубликаты), потом по номеру
+// строки (вернуть исходный порядок)
+func uniqUnsorted(input io.Reader, output io.Writer, opts uniqOptions) error {
+	s := &spiller{limit: opts.maxMemory}
+	if opts.maxMemory > 0 {
+		tmpDir, err := os.MkdirTemp(opts.tempDir, "uniq")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+		s.dir = tmpDir
+	}
+
+	groups := map[string]int{}
+	var chunk []uniqRecord
+	var keyRuns []string
+	used := 0
+
+	in := opts.scanner(input)
+	for index := 0; in.Scan(); index++ {
+		txt := in.Text()
+		key := opts.key(txt)
+		if i, ok := groups[key]; ok {
+			chunk[i].count++
+			continue
+		}
+
+		record := uniqRecord{index: index, count: 1, line: txt, key: key}
+		groups[key] = len(chunk)
+		chunk = append(chunk, record)
+		used += record.size()
+
+		if s.full(used) {
+			sortByKey(chunk)
+			run, err := s.spill(chunk)
+			if err != nil {
+				return err
+			}
+			keyRuns = append(keyRuns, run)
+			groups, chunk, used = map[string]int{}, nil, 0
+		}
+	}
+	if err := in.Err(); err != nil {
+		return err
+	}
+
+	// всё поместилось в память: chunk уже в порядке первых вхождений
+	if len(keyRuns) == 0 {
+		for _, record := range chunk {
+			opts.print(output, record.line, record.count)
+		}
+		return nil
+	}
+
+	sortByKey(chunk)
+	run, err := s.spill(chunk)
+	if err != nil {
+		return err
+	}
+	keyRuns = append(keyRuns, run)
+
+	// первый проход: одинаковые ключи из разных кусков идут подряд,
+	// первым - самое раннее вхождение
+	var indexRuns []string
+	var current *uniqRecord
+	chunk, used = nil, 0
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		chunk = append(chunk, *current)
+		used += current.size()
+		if !s.full(used) {
+			return nil
+		}
+		sortByIndex(chunk)
+		run, err := s.spill(chunk)
+		if err != nil {
+			return err
+		}
+		indexRuns = append(indexRuns, run)
+		chunk, used = nil, 0
+		return nil
+	}
+
+	err = mergeRuns(keyRuns, opts, lessByKey, func(record uniqRecord) error {
+		if current != nil && current.key == record.key {
+			current.count += record.count
+			return nil
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		current = &record
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	// второй проход: возвращаем порядок первых вхождений
+	sortByIndex(chunk)
+	if len(indexRuns) == 0 {
+		for _, record := range chunk {
+			opts.print(output, record.line, record.count)
+		}
+		return nil
+	}
+
+	run, err = s.spill(chunk)
+	if err != nil {
+		return err
+	}
+	indexRuns = append(indexRuns, run)
+
+	return mergeRuns(indexRuns, opts, lessByIndex, func(record uniqRecord) error {
+		opts.print(output, record.line, record.count)
+		return nil
+	})
+}
+
+func lessByKey(a, b uniqRecord) bool {
+	if a.key != b.key {
+		return a.key < b.key
+	}
+	return a.index < b.index
+}
+
+func lessByIndex(a, b uniqRecord) bool {
+	return a.index < b.index
+}
+
+func sortByKey(records []uniqRecord) {
+	sort.Slice(records, func(i, j int) bool { return lessByKey(records[i], records[j]) })
+}
+
+func sortByIndex(records []uniqRecord) {
+	sort.Slice(records, func(i, j int) bool { return lessByIndex(records[i], records[j]) })
+}
+
+// spiller пишет отсортированные куски во временный каталог
+type spiller struct {
+	dir   string
+	limit int
+	runs  int
+}
+
+func (s *spiller) full(used int) bool {
+	return s.limit > 0 && used >= s.limit
+}
+
+// spill сохраняет записи по одной на строку: номер, счётчик, сама строка
+// в кавычках (в csv-записи могут быть переводы строк). Ключ не пишется -
+// при чтении он заново считается из строки
+func (s *spiller) spill(records []uniqRecord) (string, error) {
+	s.runs++
+	path := filepath.Join(s.dir, fmt.Sprintf("run%d", s.runs))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	out := bufio.NewWriter(file)
+	for _, record := range records {
+		fmt.Fprintf(out, "%d\t%d\t%s\n", record.index, record.count, strconv.Quote(record.line))
+	}
+	if err := out.Flush(); err != nil {
+		return "", err
+	}
+
+	return path, file.Close()
+}
+
+type runReader struct {
+	file    *os.File
+	scanner *bufio.Scanner
+	opts    uniqOptions
+	current uniqRecord
+}
+
+func openRun(path string, opts uniqOptions) (*runReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &runReader{file: file, scanner: bufio.NewScanner(file), opts: opts}, nil
+}
+
+// next читает следующую запись в current; false - кусок закончился
+func (r *runReader) next() (bool, error) {
+	if !r.scanner.Scan() {
+		return false, r.scanner.Err()
+	}
+
+	fields := strings.SplitN(r.scanner.Text(), "\t", 3)
+	if len(fields) != 3 {
+		return false, fmt.Errorf("broken run file %s", r.file.Name())
+	}
+	index, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return false, err
+	}
+	count, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return false, err
+	}
+
+	line, err := strconv.Unquote(fields[2])
+	if err != nil {
+		return false, err
+	}
+
+	r.current = uniqRecord{index: index, count: count, line: line, key: r.opts.key(line)}
+	return true, nil
+}
+
+type runHeap struct {
+	runs []*runReader
+	less func(a, b uniqRecord) bool
+}
+
+func (h *runHeap) Len() int           { return len(h.runs) }
+func (h *runHeap) Less(i, j int) bool { return h.less(h.runs[i].current, h.runs[j].current) }
+func (h *runHeap) Swap(i, j int)      { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+func (h *runHeap) Push(x interface{}) { h.runs = append(h.runs, x.(*runReader)) }
+func (h *runHeap) Pop() interface{} {
+	last := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return last
+}
+
+// mergeRuns - k-way слияние отсортированных кусков через кучу
+func mergeRuns(paths []string, opts uniqOptions, less func(a, b uniqRecord) bool, emit func(uniqRecord) error) error {
+	h := &runHeap{less: less}
+	defer func() {
+		for _, run := range h.runs {
+			run.file.Close()
+		}
+	}()
+
+	for _, path := range paths {
+		run, err := openRun(path, opts)
+		if err != nil {
+			return err
+		}
+		ok, err := run.next()
+		if err != nil || !ok {
+			run.file.Close()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		h.runs = append(h.runs, run)
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		run := h.runs[0]
+		if err := emit(run.current); err != nil {
+			return err
+		}
+
+		ok, err := run.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+			continue
+		}
+		heap.Pop(h)
+		run.file.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+var testUnsortedInput = `b
+a
+b
+c
+a
+a
+d
+`
+
+func TestUnsorted(t *testing.T) {
+	cases := []struct {
+		name   string
+		opts   uniqOptions
+		result string
+	}{
+		{"plain", uniqOptions{}, "b\na\nc\nd\n"},
+		{"count", uniqOptions{count: true}, "      2 b\n      3 a\n      1 c\n      1 d\n"},
+		{"repeated", uniqOptions{repeated: true}, "b\na\n"},
+		{"unique", uniqOptions{unique: true}, "c\nd\n"},
+	}
+
+	for _, c := range cases {
+		for _, maxMemory := range []int{0, 1, 150} {
+			t.Run(fmt.Sprintf("%s/mem%d", c.name, maxMemory), func(t *testing.T) {
+				opts := c.opts
+				opts.unsorted = true
+				opts.maxMemory = maxMemory
+				opts.tempDir = t.TempDir()
+
+				out := bytes.NewBuffer(nil)
+				err := uniqWithOptions(bytes.NewBufferString(testUnsortedInput), out, opts)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if out.String() != c.result {
+					t.Errorf("result not match\nGot:\n%q\nExpected:\n%q", out.String(), c.result)
+				}
+
+				leftovers, _ := os.ReadDir(opts.tempDir)
+				if len(leftovers) != 0 {
+					t.Errorf("temporary files were not removed: %v", leftovers)
+				}
+			})
+		}
+	}
+}
+
+// TestUnsortedSpill сверяет работу через диск с подсчётом в памяти
+// на входе, который гарантированно не влезает в лимит
+func TestUnsortedSpill(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	lines := make([]string, 5000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("Line-%d", rnd.Intn(700))
+	}
+	input := strings.Join(lines, "\n")
+
+	opts := uniqOptions{unsorted: true, count: true, ignoreCase: true}
+	expected := bytes.NewBuffer(nil)
+	if err := uniqWithOptions(strings.NewReader(input), expected, opts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	opts.maxMemory = 2000
+	opts.tempDir = t.TempDir()
+	out := bytes.NewBuffer(nil)
+	if err := uniqWithOptions(strings.NewReader(input), out, opts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if out.String() != expected.String() {
+		t.Errorf("spilled result differs from in-memory one")
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+)
+
+// SignerCache запоминает результаты подписчика (DataSignerCrc32,
+// DataSignerMd5 и т.п.). Хранит не больше size последних значений (LRU),
+// а одновременные запросы одного и того же значения считаются один раз
+type SignerCache struct {
+	sign func(data string) string
+	size int
+
+	mu     sync.Mutex
+	lru    *list.List
+	items  map[string]*list.Element
+	calls  map[string]*signerCall
+	hits   uint64
+	misses uint64
+}
+
+type cacheEntry struct {
+	key    string
+	result string
+}
+
+// signerCall - вычисление, которого ждут все одновременные запросы ключа
+type signerCall struct {
+	done   chan struct{}
+	result string
+	// panicked и panicValue - подписчик упал; ждущие паникуют так же
+	panicked   bool
+	panicValue interface{}
+}
+
+// NewSignerCache оборачивает sign кешем на size значений (минимум 1)
+func NewSignerCache(size int, sign func(data string) string) *SignerCache {
+	if size < 1 {
+		size = 1
+	}
+	return &SignerCache{
+		sign:  sign,
+		size:  size,
+		lru:   list.New(),
+		items: make(map[string]*list.Element),
+		calls: make(map[string]*signerCall),
+	}
+}
+
+// Sign возвращает подпись data, по возможности не вызывая подписчик
+func (c *SignerCache) Sign(data string) string {
+	// соль участвует в подписи, поэтому и в ключе
+	key := DataSignerSalt + "\x00" + data
+
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.hits++
+		c.lru.MoveToFront(el)
+		c.mu.Unlock()
+		return el.Value.(*cacheEntry).result
+	}
+	if call, ok := c.calls[key]; ok {
+		c.hits++
+		c.mu.Unlock()
+		<-call.done
+		if call.panicked {
+			panic(call.panicValue)
+		}
+		return call.result
+	}
+	c.misses++
+	call := &signerCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	// если подписчик паникует, ждущие не должны зависнуть навсегда
+	// или получить пустую строку вместо подписи
+	completed := false
+	defer func() {
+		if !completed {
+			call.panicked = true
+			call.panicValue = recover()
+		}
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(call.done)
+		if call.panicked {
+			panic(call.panicValue)
+		}
+	}()
+
+	call.result = c.sign(data)
+	completed = true
+
+	c.mu.Lock()
+	c.items[key] = c.lru.PushFront(&cacheEntry{key: key, result: call.result})
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+	c.mu.Unlock()
+
+	return call.result
+}
+
+// Stats возвращает число попаданий и промахов. Запрос, дождавшийся чужого
+// вычисления, считается попаданием
+func (c *SignerCache) Stats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.hits, c.misses
+}
+
+// Len возвращает число значений в кеше
+func (c *SignerCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// CacheSigners подменяет DataSignerCrc32 и DataSignerMd5 кеширующими
+// обёртками на size значений каждая. Возвращает кеши (для счётчиков) и
+// функцию, которая вернёт исходные подписчики
+func CacheSigners(size int) (crcCache, md5Cache *SignerCache, restore func()) {
+	origCrc32, origMd5 := DataSignerCrc32, DataSignerMd5
+
+	crcCache = NewSignerCache(size, origCrc32)
+	md5Cache = NewSignerCache(size, origMd5)
+	DataSignerCrc32, DataSignerMd5 = crcCache.Sign, md5Cache.Sign
+
+	return crcCache, md5Cache, func() {
+		DataSignerCrc32, DataSignerMd5 = origCrc32, origMd5
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSignerCacheLRU(t *testing.T) {
+	var calls int32
+	cache := NewSignerCache(2, func(data string) string {
+		atomic.AddInt32(&calls, 1)
+		return "h" + data
+	})
+
+	for _, data := range []string{"1", "2", "1", "3", "1", "2"} {
+		if got := cache.Sign(data); got != "h"+data {
+			t.Errorf("Sign(%q) = %q", data, got)
+		}
+	}
+
+	// "2" вытеснен значением "3", "1" всё время оставался свежим
+	hits, misses := cache.Stats()
+	if hits != 2 || misses != 4 || calls != 4 {
+		t.Errorf("expected 2 hits, 4 misses and 4 calls, got %d, %d, %d", hits, misses, calls)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("cache grew over its size: %d", cache.Len())
+	}
+}
+
+func TestSignerCacheSingleflight(t *testing.T) {
+	var calls int32
+	cache := NewSignerCache(10, func(data string) string {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return data
+	})
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Sign("0")
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("concurrent identical requests were not deduplicated: %d calls", calls)
+	}
+	if hits, misses := cache.Stats(); hits != 9 || misses != 1 {
+		t.Errorf("expected 9 hits and 1 miss, got %d and %d", hits, misses)
+	}
+}
+
+func TestCacheSignersMultiHash(t *testing.T) {
+	original := DataSignerCrc32
+	defer func() { DataSignerCrc32 = original }()
+	var calls int32
+	DataSignerCrc32 = func(data string) string {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(time.Millisecond)
+		return data
+	}
+
+	crcCache, _, restore := CacheSigners(100)
+	defer restore()
+
+	values := []string{"a", "b", "a", "a", "b"}
+	results, err := RunStage(context.Background(), MultiHashStageWith(StageOptions{Ordered: true}), values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(values) {
+		t.Fatalf("expected %d results, got %d", len(values), len(results))
+	}
+	for i, data := range values {
+		expected := ""
+		for th := 0; th < 6; th++ {
+			expected += strconv.Itoa(th) + data
+		}
+		if results[i] != expected {
+			t.Errorf("result %d = %q, expected %q", i, results[i], expected)
+		}
+	}
+
+	if calls != 12 {
+		t.Errorf("expected 12 crc32 calls for 2 distinct values, got %d", calls)
+	}
+	if hits, misses := crcCache.Stats(); hits != 18 || misses != 12 {
+		t.Errorf("expected 18 hits and 12 misses, got %d and %d", hits, misses)
+	}
+}
+
+func TestSignerCachePanic(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	cache := NewSignerCache(10, func(data string) string {
+		close(started)
+		<-release
+		panic("signer broke")
+	})
+
+	panics := make(chan interface{}, 2)
+	sign := func() {
+		defer func() { panics <- recover() }()
+		cache.Sign("0")
+	}
+
+	go sign()
+	<-started
+	go sign()
+	// второй вызов должен встать в ожидание первого
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		if hits, _ := cache.Stats(); hits == 1 {
+			break
+		}
+		if time.Since(start) > time.Second {
+			t.Fatal("second caller did not wait for the first")
+		}
+	}
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if r := <-panics; r != "signer broke" {
+			t.Errorf("caller %d: expected signer panic, got %v", i, r)
+		}
+	}
+	if cache.Len() != 0 {
+		t.Errorf("failed result was cached")
+	}
+}
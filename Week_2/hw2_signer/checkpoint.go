@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Checkpoint - журнал посчитанных результатов звеньев. Каждая запись -
+// строка "звено\tсоль\tвход\tрезультат" (всё через strconv.Quote), файл
+// только дописывается. При повторном запуске с тем же журналом уже
+// посчитанные значения берутся из него, а не считаются заново
+type Checkpoint struct {
+	mu      sync.Mutex
+	file    *os.File
+	results map[string]string
+}
+
+// OpenCheckpoint открывает (или создаёт) журнал path и читает записи из него.
+// Недописанная последняя строка - след прерванного запуска - отбрасывается
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{file: file, results: make(map[string]string)}
+	valid, err := cp.load(file)
+	if err == nil {
+		// обрезаем хвост, чтобы новые записи не склеились с оборванной строкой
+		if err = file.Truncate(valid); err == nil {
+			_, err = file.Seek(valid, io.SeekStart)
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// load читает записи и возвращает длину корректной части файла
+func (cp *Checkpoint) load(r io.Reader) (int64, error) {
+	reader := bufio.NewReader(r)
+	var valid int64
+	for num := 1; ; num++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return valid, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		fields := strings.Split(string(bytes.TrimSuffix(line, []byte("\n"))), "\t")
+		if len(fields) != 4 {
+			return 0, fmt.Errorf("line %d: expected 4 fields, got %d", num, len(fields))
+		}
+		for i, field := range fields {
+			if fields[i], err = strconv.Unquote(field); err != nil {
+				return 0, fmt.Errorf("line %d: %w", num, err)
+			}
+		}
+
+		cp.results[checkpointKey(fields[0], fields[1], fields[2])] = fields[3]
+		valid += int64(len(line))
+	}
+}
+
+// результат зависит от соли, поэтому она тоже часть ключа
+func checkpointKey(stage, salt, input string) string {
+	return stage + "\x00" + salt + "\x00" + input
+}
+
+// Lookup возвращает сохранённый результат звена stage для input
+func (cp *Checkpoint) Lookup(stage, input string) (string, bool) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	result, ok := cp.results[checkpointKey(stage, DataSignerSalt, input)]
+	return result, ok
+}
+
+// Record дописывает результат в журнал. Запись уходит в файл сразу, так что
+// переживает падение процесса
+func (cp *Checkpoint) Record(stage, input, result string) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	key := checkpointKey(stage, DataSignerSalt, input)
+	if _, ok := cp.results[key]; ok {
+		return nil
+	}
+
+	line := strconv.Quote(stage) + "\t" + strconv.Quote(DataSignerSalt) + "\t" +
+		strconv.Quote(input) + "\t" + strconv.Quote(result) + "\n"
+	if _, err := cp.file.WriteString(line); err != nil {
+		return err
+	}
+	cp.results[key] = result
+	return nil
+}
+
+// Len возвращает число сохранённых результатов
+func (cp *Checkpoint) Len() int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	return len(cp.results)
+}
+
+// Close закрывает файл журнала
+func (cp *Checkpoint) Close() error {
+	return cp.file.Close()
+}
+
+// checkpointed берёт результат fn из журнала cp, а посчитанный записывает
+// туда же. Без журнала возвращает fn как есть
+func checkpointed(cp *Checkpoint, stage string, fn func(ctx context.Context, data string) (string, error)) func(ctx context.Context, data string) (string, error) {
+	if cp == nil {
+		return fn
+	}
+
+	return func(ctx context.Context, data string) (string, error) {
+		if result, ok := cp.Lookup(stage, data); ok {
+			return result, nil
+		}
+
+		result, err := fn(ctx, data)
+		if err != nil {
+			return "", err
+		}
+		if err := cp.Record(stage, data, result); err != nil {
+			return "", fmt.Errorf("checkpoint: %w", err)
+		}
+		return result, nil
+	}
+}
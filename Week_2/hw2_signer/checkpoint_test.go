@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeSigners подменяет подписчиков быстрыми и считает их вызовы
+func fakeSigners(t *testing.T) *int32 {
+	crc32, md5 := DataSignerCrc32, DataSignerMd5
+	t.Cleanup(func() { DataSignerCrc32, DataSignerMd5 = crc32, md5 })
+
+	var calls int32
+	DataSignerCrc32 = func(data string) string {
+		atomic.AddInt32(&calls, 1)
+		return "c(" + data + ")"
+	}
+	DataSignerMd5 = func(data string) string {
+		atomic.AddInt32(&calls, 1)
+		return "m(" + data + ")"
+	}
+	return &calls
+}
+
+func runSigner(t *testing.T, cp *Checkpoint, input ...int) string {
+	t.Helper()
+	opts := StageOptions{Checkpoint: cp}
+	var result string
+	ExecutePipeline(
+		func(in, out chan interface{}) {
+			for _, value := range input {
+				out <- value
+			}
+		},
+		SingleHashWith(opts),
+		MultiHashWith(opts),
+		CombineResults,
+		func(in, out chan interface{}) {
+			result = (<-in).(string)
+		},
+	)
+	return result
+}
+
+func TestCheckpointResume(t *testing.T) {
+	calls := fakeSigners(t)
+	input := []int{0, 1, 1, 2, 3, 5, 8}
+	expected := runSigner(t, nil, input...)
+
+	path := filepath.Join(t.TempDir(), "signer.checkpoint")
+	cp, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "прерванный" запуск успел посчитать только начало
+	runSigner(t, cp, input[:3]...)
+	cp.Close()
+
+	cp, err = OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	if cp.Len() != 4 {
+		t.Errorf("expected 4 saved results for 2 distinct values, got %d", cp.Len())
+	}
+
+	atomic.StoreInt32(calls, 0)
+	result := runSigner(t, cp, input...)
+	if result != expected {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", result, expected)
+	}
+	// 2, 3, 5, 8: по 3 вызова на SingleHash и 6 на MultiHash
+	if *calls != 4*9 {
+		t.Errorf("saved values were computed again: %d signer calls", *calls)
+	}
+}
+
+func TestCheckpointTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signer.checkpoint")
+	cp, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.Record("single_hash", "0", "a~b"); err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+
+	// процесс упал посреди записи
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`"single_hash"` + "\t" + `"" "1`)
+	f.Close()
+
+	cp, err = OpenCheckpoint(path)
+	if err != nil {
+		t.Fatalf("torn tail was not dropped: %v", err)
+	}
+	if err := cp.Record("single_hash", "1", "c~d"); err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+
+	cp, err = OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	if result, ok := cp.Lookup("single_hash", "1"); !ok || result != "c~d" {
+		t.Errorf("record after torn tail was lost: %q, %v", result, ok)
+	}
+	if result, ok := cp.Lookup("single_hash", "0"); !ok || result != "a~b" {
+		t.Errorf("record before torn tail was lost: %q, %v", result, ok)
+	}
+}
+
+func TestCheckpointCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signer.checkpoint")
+	os.WriteFile(path, []byte("garbage\n"), 0o644)
+
+	if _, err := OpenCheckpoint(path); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected error about line 1, got %v", err)
+	}
+}
+
+func TestCheckpointSalt(t *testing.T) {
+	cp, err := OpenCheckpoint(filepath.Join(t.TempDir(), "signer.checkpoint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+
+	cp.Record("multi_hash", "0", "unsalted")
+	salt := DataSignerSalt
+	DataSignerSalt = "salt"
+	defer func() { DataSignerSalt = salt }()
+
+	if _, ok := cp.Lookup("multi_hash", "0"); ok {
+		t.Errorf("result computed with another salt was reused")
+	}
+}
+
+func TestCheckpointConfig(t *testing.T) {
+	calls := fakeSigners(t)
+	path := filepath.Join(t.TempDir(), "signer.checkpoint")
+	config := "input: [0, 1]\ncheckpoint: " + strconv.Quote(path) + "\nstages:\n  - name: single_hash\n  - name: multi_hash\n  - name: combine\n"
+
+	first := runConfig(t, config)
+	atomic.StoreInt32(calls, 0)
+	if second := runConfig(t, config); second != first || *calls != 0 {
+		t.Errorf("second run did not reuse checkpoint: %d calls\nGot:\n%v\nExpected:\n%v", *calls, second, first)
+	}
+}
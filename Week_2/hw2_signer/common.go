@@ -1,11 +1,11 @@
 package main
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"hash/crc32"
 	"strconv"
-	"sync/atomic"
 	"time"
 )
 
@@ -20,26 +20,18 @@ var (
 	DataSignerSalt            = ""
 )
 
+// OverheatLock - защита самого DataSignerMd5 от одновременных вызовов. Раньше
+// здесь был цикл с CAS и секундным sleep, теперь ждём в честной очереди
+// Md5Limiter; слот, уже полученный в SignMd5, берётся без второго ожидания
 var OverheatLock = func() {
-	for {
-		if swapped := atomic.CompareAndSwapUint32(&dataSignerOverheat, 0, 1); !swapped {
-			fmt.Println("OverheatLock happend")
-			time.Sleep(time.Second)
-		} else {
-			break
-		}
+	if takeMd5Handoff() {
+		return
 	}
+	Md5Limiter.Acquire(context.Background())
 }
 
 var OverheatUnlock = func() {
-	for {
-		if swapped := atomic.CompareAndSwapUint32(&dataSignerOverheat, 1, 0); !swapped {
-			fmt.Println("OverheatUnlock happend")
-			time.Sleep(time.Second)
-		} else {
-			break
-		}
-	}
+	Md5Limiter.Release()
 }
 
 var DataSignerMd5 = func(data string) string {
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PipelineConfig - описание конвейера: входные значения, звенья по порядку,
+// необязательный файл журнала (см. Checkpoint) и алгоритмы подписи по
+// именам из SignerByName (по умолчанию crc32 и md5)
+type PipelineConfig struct {
+	Input       []interface{} `json:"input"`
+	Stages      []StageConfig `json:"stages"`
+	Checkpoint  string        `json:"checkpoint"`
+	Signer      string        `json:"signer"`
+	InnerSigner string        `json:"inner_signer"`
+}
+
+// StageConfig - одно звено: имя из реестра, ограничения и параметры звена
+type StageConfig struct {
+	Name    string                 `json:"name"`
+	Workers int                    `json:"workers"`
+	Buffer  int                    `json:"buffer"`
+	Ordered bool                   `json:"ordered"`
+	Params  map[string]interface{} `json:"params"`
+
+	// общие для всего конвейера журнал и алгоритмы, их раздаёт build
+	shared StageOptions
+}
+
+func (cfg StageConfig) options() StageOptions {
+	opts := cfg.shared
+	opts.Workers, opts.Buffer, opts.Ordered = cfg.Workers, cfg.Buffer, cfg.Ordered
+	return opts
+}
+
+// param возвращает параметр строкой: в JSON и YAML числа и флаги пишут без кавычек
+func (cfg StageConfig) param(name string) (string, bool) {
+	value, ok := cfg.Params[name]
+	if !ok || value == nil {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
+func (cfg StageConfig) paramDefault(name, def string) string {
+	if value, ok := cfg.param(name); ok {
+		return value
+	}
+	return def
+}
+
+func (cfg StageConfig) paramBool(name string) (bool, error) {
+	value, ok := cfg.param(name)
+	if !ok {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: bad %s %q", cfg.Name, name, value)
+	}
+	return b, nil
+}
+
+// LoadPipelineConfig читает описание из файла; формат - по расширению
+// (.json, .yaml или .yml)
+func LoadPipelineConfig(path string) (*PipelineConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := filepath.Ext(path)
+	if format != "" {
+		format = format[1:]
+	}
+	cfg, err := ParsePipelineConfig(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ParsePipelineConfig разбирает описание в формате json, yaml или yml.
+// YAML сводится к JSON, так что проверки полей у форматов общие
+func ParsePipelineConfig(data []byte, format string) (*PipelineConfig, error) {
+	switch format {
+	case "json":
+	case "yaml", "yml":
+		var tree interface{}
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+		var err error
+		if data, err = json.Marshal(tree); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown pipeline format %q", format)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// числа во входе остаются как записаны: 12345678901, а не 1.2345678901e+10
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+
+	cfg := &PipelineConfig{}
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Build собирает звенья конвейера; первым идёт источник, отдающий Input.
+// Журнал Build не открывает - это делает RunPipelineConfig
+func (c *PipelineConfig) Build() ([]contextJob, error) {
+	return c.build(nil)
+}
+
+func (c *PipelineConfig) build(cp *Checkpoint) ([]contextJob, error) {
+	if len(c.Stages) == 0 {
+		return nil, fmt.Errorf("pipeline has no stages")
+	}
+
+	shared := StageOptions{Checkpoint: cp}
+	var err error
+	if c.Signer != "" {
+		if shared.Signer, err = SignerByName(c.Signer); err != nil {
+			return nil, err
+		}
+	}
+	if c.InnerSigner != "" {
+		if shared.InnerSigner, err = SignerByName(c.InnerSigner); err != nil {
+			return nil, err
+		}
+	}
+
+	jobs := make([]contextJob, 0, len(c.Stages)+1)
+	jobs = append(jobs, c.source())
+	for i, stage := range c.Stages {
+		stage.shared = shared
+		factory, err := lookupStage(stage.Name)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d: %w", i, err)
+		}
+		j, err := factory(stage)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d (%s): %w", i, stage.Name, err)
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+func (c *PipelineConfig) source() contextJob {
+	return func(ctx context.Context, in, out chan interface{}) error {
+		for _, value := range c.Input {
+			if err := sendContext(ctx, out, fmt.Sprint(value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// RunPipelineConfig собирает конвейер по c, выполняет его и печатает в w
+// всё, что отдало последнее звено, по значению в строке
+func RunPipelineConfig(ctx context.Context, c *PipelineConfig, w io.Writer) error {
+	var cp *Checkpoint
+	if c.Checkpoint != "" {
+		var err error
+		if cp, err = OpenCheckpoint(c.Checkpoint); err != nil {
+			return err
+		}
+		defer cp.Close()
+	}
+
+	jobs, err := c.build(cp)
+	if err != nil {
+		return err
+	}
+
+	jobs = append(jobs, func(ctx context.Context, in, out chan interface{}) error {
+		for data := range in {
+			if _, err := fmt.Fprintln(w, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return ExecutePipelineContext(ctx, jobs...)
+}
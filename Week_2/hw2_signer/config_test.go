@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPipelineConfigSigner(t *testing.T) {
+	yamlConfig := `
+# как в TestStageSigner
+input: [0, 1]
+stages:
+  - name: single_hash
+    workers: 2
+  - name: multi_hash
+  - name: combine
+`
+	jsonConfig := `{"input": [0, 1], "stages": [{"name": "single_hash", "workers": 2}, {"name": "multi_hash"}, {"name": "combine"}]}`
+
+	fromYAML, err := ParsePipelineConfig([]byte(yamlConfig), "yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fromJSON, err := ParsePipelineConfig([]byte(jsonConfig), "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Fatalf("yaml and json configs differ\nYAML: %+v\nJSON: %+v", fromYAML, fromJSON)
+	}
+
+	out := &bytes.Buffer{}
+	if err := RunPipelineConfig(context.Background(), fromYAML, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "29568666068035183841425683795340791879727309630931025356555_4958044192186797981418233587017209679042592862002427381542\n"
+	if out.String() != expected {
+		t.Errorf("results not match\nGot: %v\nExpected: %v", out.String(), expected)
+	}
+}
+
+func TestPipelineConfigFile(t *testing.T) {
+	cfg, err := LoadPipelineConfig("pipeline.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, stage := range cfg.Stages {
+		names = append(names, stage.Name)
+	}
+	if len(cfg.Input) != 7 || strings.Join(names, ",") != "single_hash,multi_hash,combine" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestPipelineConfigErrors(t *testing.T) {
+	cases := []struct {
+		format string
+		config string
+		err    string
+	}{
+		{"json", `{"stages": [{"name": "nope"}]}`, `unknown stage "nope"`},
+		{"json", `{"stages": [{"name": "filter", "wokers": 2}]}`, `unknown field "wokers"`},
+		{"json", `{"stages": []}`, "no stages"},
+		{"yaml", "stages:\n  - name: batch\n    params:\n      size: 0\n", "size must be a positive number"},
+		{"yaml", "stages:\n  - name: map\n    wokers: 2\n", `unknown field "wokers"`},
+		{"yaml", "stages:\n  - name: map\n   oops: 1\n", "yaml: line"},
+		{"toml", "", "unknown pipeline format"},
+	}
+
+	for _, c := range cases {
+		cfg, err := ParsePipelineConfig([]byte(c.config), c.format)
+		if err == nil {
+			_, err = cfg.Build()
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("config %q: expected error with %q, got %v", c.config, c.err, err)
+		}
+	}
+}
+
+func TestPipelineConfigYAMLValues(t *testing.T) {
+	cfg, err := ParsePipelineConfig([]byte("input: [12345678901, -12, \"007\", it's fine]\nstages:\n  - name: map\n    params: {case: upper, sep: \"# not a comment\"} # comment\n"), "yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, _ := json.Marshal(cfg.Input)
+	if expected := `[12345678901,-12,"007","it's fine"]`; string(got) != expected {
+		t.Errorf("input not match\nGot:\n%s\nExpected:\n%s", got, expected)
+	}
+	if cfg.Stages[0].paramDefault("sep", "") != "# not a comment" || cfg.Stages[0].paramDefault("case", "") != "upper" {
+		t.Errorf("unexpected params: %+v", cfg.Stages[0].Params)
+	}
+}
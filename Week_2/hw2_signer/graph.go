@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FanOut - как выход узла делится между следующими узлами
+type FanOut int
+
+const (
+	// Broadcast - каждое значение уходит во все следующие узлы
+	Broadcast FanOut = iota
+	// RoundRobin - значения раздаются следующим узлам по очереди
+	RoundRobin
+)
+
+// Graph собирает конвейер произвольной формы без циклов. Узел с несколькими
+// входами получает слитые значения всех предыдущих узлов (fan-in), узел с
+// несколькими выходами раздаёт значения по своему FanOut. Ошибки построения
+// копятся и возвращаются из Validate и Run
+type Graph struct {
+	nodes map[string]*graphNode
+	order []string
+	errs  []error
+}
+
+type graphNode struct {
+	name   string
+	job    contextJob
+	fanOut FanOut
+	next   []string
+	prev   []string
+}
+
+// NewGraph создаёт пустой граф
+func NewGraph() *Graph {
+	return &Graph{nodes: make(map[string]*graphNode)}
+}
+
+// Node добавляет узел name, выполняющий j
+func (g *Graph) Node(name string, j contextJob) *Graph {
+	switch {
+	case j == nil:
+		g.errs = append(g.errs, fmt.Errorf("node %q: nil job", name))
+	case g.nodes[name] != nil:
+		g.errs = append(g.errs, fmt.Errorf("node %q added twice", name))
+	default:
+		g.nodes[name] = &graphNode{name: name, job: j}
+		g.order = append(g.order, name)
+	}
+	return g
+}
+
+// Connect направляет выход узла from во все узлы to. Узлы должны быть уже
+// добавлены через Node
+func (g *Graph) Connect(from string, to ...string) *Graph {
+	src := g.nodes[from]
+	if src == nil {
+		g.errs = append(g.errs, fmt.Errorf("connect: unknown node %q", from))
+		return g
+	}
+
+	for _, name := range to {
+		dst := g.nodes[name]
+		switch {
+		case dst == nil:
+			g.errs = append(g.errs, fmt.Errorf("connect: unknown node %q", name))
+		case containsString(src.next, name):
+			g.errs = append(g.errs, fmt.Errorf("connect: %q -> %q added twice", from, name))
+		default:
+			src.next = append(src.next, name)
+			dst.prev = append(dst.prev, from)
+		}
+	}
+	return g
+}
+
+// FanOut задаёт, как узел name делит выход между следующими узлами
+func (g *Graph) FanOut(name string, mode FanOut) *Graph {
+	node := g.nodes[name]
+	if node == nil {
+		g.errs = append(g.errs, fmt.Errorf("fan-out: unknown node %q", name))
+		return g
+	}
+	node.fanOut = mode
+	return g
+}
+
+// Validate возвращает ошибки построения и находит циклы
+func (g *Graph) Validate() error {
+	if len(g.errs) > 0 {
+		return errors.Join(g.errs...)
+	}
+	if len(g.nodes) == 0 {
+		return fmt.Errorf("graph has no nodes")
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g.nodes))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			// цикл - хвост пути, начиная с первого появления name
+			for i, n := range path {
+				if n == name {
+					return fmt.Errorf("cycle: %s -> %s", strings.Join(path[i:], " -> "), name)
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, next := range g.nodes[name].next {
+			if err := visit(next); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range g.order {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run проверяет граф и выполняет его. Как и в ExecutePipelineContext, первая
+// ошибка узла отменяет остальные и возвращается, а все каналы дочитываются
+func (g *Graph) Run(ctx context.Context) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+	once := &sync.Once{}
+	var firstErr error
+
+	inputs := make(map[string]chan interface{}, len(g.nodes))
+	senders := make(map[string]*sync.WaitGroup, len(g.nodes))
+	for _, name := range g.order {
+		inputs[name] = make(chan interface{})
+		senders[name] = &sync.WaitGroup{}
+		senders[name].Add(len(g.nodes[name].prev))
+	}
+
+	// вход закрывается, когда закончили все предыдущие узлы; у истоков их нет
+	for _, name := range g.order {
+		go func(in chan interface{}, senders *sync.WaitGroup) {
+			senders.Wait()
+			close(in)
+		}(inputs[name], senders[name])
+	}
+
+	for _, name := range g.order {
+		node := g.nodes[name]
+		out := make(chan interface{})
+
+		wg.Add(1)
+		go func(node *graphNode, in, out chan interface{}) {
+			defer wg.Done()
+			if err := runContextJob(ctx, node.job, in, out); err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("node %q: %w", node.name, err)
+					cancel()
+				})
+			}
+			drain(in)
+		}(node, inputs[name], out)
+
+		targets := make([]chan interface{}, 0, len(node.next))
+		done := make([]*sync.WaitGroup, 0, len(node.next))
+		for _, next := range node.next {
+			targets = append(targets, inputs[next])
+			done = append(done, senders[next])
+		}
+
+		wg.Add(1)
+		go func(mode FanOut, out chan interface{}) {
+			defer wg.Done()
+			defer func() {
+				for _, d := range done {
+					d.Done()
+				}
+			}()
+			distribute(ctx, mode, out, targets)
+		}(node.fanOut, out)
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+// distribute раздаёт выход узла следующим узлам. Без следующих узлов (сток)
+// или после отмены значения просто вычитываются
+func distribute(ctx context.Context, mode FanOut, out chan interface{}, targets []chan interface{}) {
+	turn := 0
+	for data := range out {
+		if len(targets) == 0 {
+			continue
+		}
+
+		if mode == RoundRobin {
+			sendContext(ctx, targets[turn], data)
+			turn = (turn + 1) % len(targets)
+			continue
+		}
+		for _, target := range targets {
+			if sendContext(ctx, target, data) != nil {
+				break
+			}
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func sourceJob(values ...int) contextJob {
+	return func(ctx context.Context, in, out chan interface{}) error {
+		for _, value := range values {
+			if err := sendContext(ctx, out, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func mapJob(fn func(int) int) contextJob {
+	return func(ctx context.Context, in, out chan interface{}) error {
+		for data := range in {
+			if err := sendContext(ctx, out, fn(data.(int))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestGraphBroadcastMerge(t *testing.T) {
+	sum := 0
+	err := NewGraph().
+		Node("source", sourceJob(1, 2, 3, 4, 5)).
+		Node("double", mapJob(func(x int) int { return x * 2 })).
+		Node("square", mapJob(func(x int) int { return x * x })).
+		Node("sum", func(ctx context.Context, in, out chan interface{}) error {
+			for data := range in {
+				sum += data.(int)
+			}
+			return nil
+		}).
+		Connect("source", "double", "square").
+		Connect("double", "sum").
+		Connect("square", "sum").
+		Run(context.Background())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 2*(1+..+5) + (1+4+9+16+25)
+	if sum != 30+55 {
+		t.Errorf("expected %d, got %d", 85, sum)
+	}
+}
+
+func TestGraphRoundRobin(t *testing.T) {
+	mu := &sync.Mutex{}
+	counts := map[string]int{}
+	worker := func(name string) contextJob {
+		return func(ctx context.Context, in, out chan interface{}) error {
+			for range in {
+				mu.Lock()
+				counts[name]++
+				mu.Unlock()
+			}
+			return nil
+		}
+	}
+
+	err := NewGraph().
+		Node("source", sourceJob(0, 1, 2, 3, 4, 5, 6, 7, 8, 9)).
+		Node("a", worker("a")).
+		Node("b", worker("b")).
+		Connect("source", "a", "b").
+		FanOut("source", RoundRobin).
+		Run(context.Background())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if counts["a"] != 5 || counts["b"] != 5 {
+		t.Errorf("values were not split evenly: %v", counts)
+	}
+}
+
+func TestGraphValidate(t *testing.T) {
+	pass := mapJob(func(x int) int { return x })
+
+	err := NewGraph().
+		Node("a", pass).Node("b", pass).Node("c", pass).
+		Connect("a", "b").Connect("b", "c").Connect("c", "a").
+		Run(context.Background())
+	if err == nil || err.Error() != "cycle: a -> b -> c -> a" {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+
+	err = NewGraph().
+		Node("a", pass).Node("a", pass).Node("b", nil).
+		Connect("a", "missing").FanOut("missing", RoundRobin).
+		Validate()
+	for _, expected := range []string{`node "a" added twice`, `node "b": nil job`, `connect: unknown node "missing"`, `fan-out: unknown node "missing"`} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error with %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestGraphError(t *testing.T) {
+	errStop := errors.New("stop")
+	err := NewGraph().
+		Node("source", func(ctx context.Context, in, out chan interface{}) error {
+			for i := 0; ; i++ {
+				if err := sendContext(ctx, out, i); err != nil {
+					return err
+				}
+			}
+		}).
+		Node("fail", func(ctx context.Context, in, out chan interface{}) error {
+			for data := range in {
+				if data.(int) == 10 {
+					return errStop
+				}
+			}
+			return nil
+		}).
+		Node("sink", mapJob(func(x int) int { return x })).
+		Connect("source", "fail", "sink").
+		Run(context.Background())
+
+	if !errors.Is(err, errStop) || !strings.Contains(err.Error(), `node "fail"`) {
+		t.Errorf("expected errStop from fail, got %v", err)
+	}
+}
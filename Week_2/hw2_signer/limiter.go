@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limiter - семафор со справедливой очередью: слоты выдаются строго в порядке
+// обращения, ожидание прерывается контекстом, никаких sleep и опроса
+type Limiter struct {
+	mu      sync.Mutex
+	limit   int
+	active  int
+	waiters []chan struct{}
+	// onWait узнаёт, сколько Acquire простоял в очереди
+	onWait func(d time.Duration)
+}
+
+// NewLimiter создаёт Limiter на limit одновременных владельцев (минимум 1)
+func NewLimiter(limit int) *Limiter {
+	l := &Limiter{}
+	l.SetLimit(limit)
+	return l
+}
+
+// SetLimit меняет число слотов. При уменьшении уже выданные слоты не
+// отбираются, новые просто не выдаются, пока владельцев не станет меньше
+func (l *Limiter) SetLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.grant()
+}
+
+// SetWaitHook ставит функцию, которой сообщается время каждого ожидания в
+// очереди (если слот свободен сразу, она не вызывается), и возвращает прежнюю
+func (l *Limiter) SetWaitHook(hook func(d time.Duration)) func(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	prev := l.onWait
+	l.onWait = hook
+	return prev
+}
+
+// Acquire ждёт свободный слот. Если ctx отменён раньше, слот не занимается и
+// возвращается ctx.Err()
+func (l *Limiter) Acquire(ctx context.Context) error {
+	l.mu.Lock()
+	if l.active < l.limit && len(l.waiters) == 0 {
+		l.active++
+		l.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	onWait := l.onWait
+	l.mu.Unlock()
+
+	if onWait != nil {
+		start := time.Now()
+		defer func() { onWait(time.Since(start)) }()
+	}
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, w := range l.waiters {
+		if w == ready {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			// ушедший из головы очереди мог задерживать следующих
+			l.grant()
+			return ctx.Err()
+		}
+	}
+
+	// слот успели выдать одновременно с отменой - возвращаем его
+	l.active--
+	l.grant()
+	return ctx.Err()
+}
+
+// Release освобождает слот, полученный через Acquire
+func (l *Limiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active == 0 {
+		panic("signer: Release without Acquire")
+	}
+	l.active--
+	l.grant()
+}
+
+// grant раздаёт свободные слоты первым в очереди; вызывается под l.mu
+func (l *Limiter) grant() {
+	for l.active < l.limit && len(l.waiters) > 0 {
+		l.active++
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+	}
+}
+
+// Md5Limiter - единственная очередь к DataSignerMd5: в ней ждут и SignMd5, и
+// OverheatLock. По умолчанию - один вызов, как того требует сам подписчик;
+// больше имеет смысл только с подменённым DataSignerMd5
+var Md5Limiter = NewLimiter(1)
+
+// md5Handoff - слоты Md5Limiter, которые SignMd5 уже получил и передаёт
+// OverheatLock внутри DataSignerMd5. Слоты друг от друга не отличаются, так
+// что неважно, кто какой заберёт: главное, что каждый будет освобождён
+var md5Handoff int32
+
+// takeMd5Handoff забирает один переданный слот, если он есть
+func takeMd5Handoff() bool {
+	for {
+		n := atomic.LoadInt32(&md5Handoff)
+		if n == 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&md5Handoff, n, n-1) {
+			return true
+		}
+	}
+}
+
+// SignMd5 вызывает DataSignerMd5, дождавшись своей очереди в Md5Limiter.
+// Ожидание прерывается ctx, а OverheatLock внутри подписчика получает уже
+// занятый слот и второй раз в очередь не встаёт
+func SignMd5(ctx context.Context, data string) (string, error) {
+	if err := Md5Limiter.Acquire(ctx); err != nil {
+		return "", err
+	}
+	atomic.AddInt32(&md5Handoff, 1)
+	defer func() {
+		// подменённый DataSignerMd5 мог не вызвать OverheatLock - тогда
+		// слот так и не передан и освобождается здесь
+		if takeMd5Handoff() {
+			Md5Limiter.Release()
+		}
+	}()
+
+	return DataSignerMd5(data), nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterFIFO(t *testing.T) {
+	l := NewLimiter(1)
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var order []int
+	mu := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l.Acquire(context.Background())
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			l.Release()
+		}(i)
+		// ждём, пока горутина встанет в очередь, чтобы порядок был известен
+		waitQueue(t, l, i+1)
+	}
+
+	l.Release()
+	wg.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("waiters were not served in order: %v", order)
+		}
+	}
+}
+
+func TestLimiterContext(t *testing.T) {
+	l := NewLimiter(1)
+	l.Acquire(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+
+	// отменённое ожидание не должно занимать слот
+	l.Release()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := l.Acquire(ctx); err != nil {
+		t.Errorf("slot was leaked by cancelled waiter: %v", err)
+	}
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l := NewLimiter(3)
+	var active, peak int32
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Acquire(context.Background())
+			defer l.Release()
+
+			n := atomic.AddInt32(&active, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&active, -1)
+		}()
+	}
+	wg.Wait()
+
+	if peak > 3 {
+		t.Errorf("limit exceeded: %d holders at once", peak)
+	}
+}
+
+func TestSignMd5Context(t *testing.T) {
+	Md5Limiter.Acquire(context.Background())
+	defer Md5Limiter.Release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := SignMd5(ctx, "0"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected Canceled, got %v", err)
+	}
+}
+
+func TestSignMd5Limit(t *testing.T) {
+	Md5Limiter.SetLimit(8)
+	defer Md5Limiter.SetLimit(1)
+
+	// настоящий DataSignerMd5 спит 10 мс; если OverheatLock ждал бы в своей
+	// очереди, 8 вызовов шли бы по одному - 80 мс
+	start := time.Now()
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			SignMd5(context.Background(), "0")
+		}()
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("md5 calls were not run concurrently: %s", elapsed)
+	}
+	Md5Limiter.mu.Lock()
+	active := Md5Limiter.active
+	Md5Limiter.mu.Unlock()
+	if active != 0 || atomic.LoadInt32(&md5Handoff) != 0 {
+		t.Errorf("slots leaked: %d active, %d handed off", active, md5Handoff)
+	}
+}
+
+func waitQueue(t *testing.T, l *Limiter, n int) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		l.mu.Lock()
+		waiting := len(l.waiters)
+		l.mu.Unlock()
+		if waiting == n {
+			return
+		}
+	}
+	t.Fatalf("%d waiters did not queue up", n)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// trackCrc32 подменяет DataSignerCrc32 быстрой версией, которая запоминает
+// наибольшее число одновременных вызовов
+func trackCrc32(t *testing.T) *int32 {
+	original := DataSignerCrc32
+	var current, peak int32
+	DataSignerCrc32 = func(data string) string {
+		now := atomic.AddInt32(&current, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+		return data
+	}
+	t.Cleanup(func() { DataSignerCrc32 = original })
+	return &peak
+}
+
+func TestMultiHashWorkers(t *testing.T) {
+	peak := trackCrc32(t)
+
+	values := make([]string, 50)
+	for i := range values {
+		values[i] = strconv.Itoa(i)
+	}
+
+	results, err := RunStage(context.Background(), MultiHashStageWith(StageOptions{Workers: 2, Buffer: 4}), values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(values) {
+		t.Errorf("expected %d results, got %d", len(values), len(results))
+	}
+	// 2 значения по 6 хешей
+	if *peak > 12 {
+		t.Errorf("too many concurrent DataSignerCrc32 calls: %d", *peak)
+	}
+}
+
+func TestParallelBackpressure(t *testing.T) {
+	var read, calls int32
+	release := make(chan struct{})
+	started := &sync.WaitGroup{}
+	started.Add(3)
+
+	stage := Parallel(3, func(ctx context.Context, value int) (int, error) {
+		if atomic.AddInt32(&calls, 1) <= 3 {
+			started.Done()
+		}
+		<-release
+		return value, nil
+	})
+
+	in := make(chan int)
+	out := make(chan int, 100)
+	go func() {
+		defer close(in)
+		for i := 0; i < 10; i++ {
+			in <- i
+			atomic.AddInt32(&read, 1)
+		}
+	}()
+
+	done := make(chan error, 1)
+	go func() { done <- stage(context.Background(), in, out) }()
+
+	started.Wait()
+	time.Sleep(20 * time.Millisecond)
+	// 3 значения в работе и одно, на котором звено ждёт свободного воркера
+	if got := atomic.LoadInt32(&read); got > 4 {
+		t.Errorf("stage read %d values while only 3 workers are allowed", got)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(out) != 10 {
+		t.Errorf("expected 10 results, got %d", len(out))
+	}
+}
+
+func TestSignerWorkersTime(t *testing.T) {
+	signer := Chain(Chain(SingleHashStageWith(StageOptions{Workers: 7}), MultiHashStageWith(StageOptions{Workers: 7, Buffer: 7})), CombineResultsStage)
+
+	start := time.Now()
+	results, err := RunStage(context.Background(), signer, []string{"0", "1", "1", "2", "3", "5", "8"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if end := time.Since(start); end > 3*time.Second {
+		t.Errorf("execition too long\nGot: %s\nExpected: <%s", end, 3*time.Second)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected single combined result, got %v", results)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// go run . -config pipeline.yaml [-checkpoint file] [values...] - собирает
+// конвейер по описанию и печатает результат; значения из командной строки
+// заменяют input конфига
+func main() {
+	config := flag.String("config", "", "pipeline description (.json, .yaml or .yml)")
+	checkpoint := flag.String("checkpoint", "", "checkpoint file to resume from (overrides the config)")
+	signer := flag.String("signer", "", "signer instead of crc32 (overrides the config)")
+	innerSigner := flag.String("inner-signer", "", "signer instead of md5 in single_hash (overrides the config)")
+	flag.Parse()
+
+	if *config == "" {
+		fmt.Fprintln(os.Stderr, "usage: go run . -config pipeline.yaml [-checkpoint file] [values...]")
+		os.Exit(2)
+	}
+
+	cfg, err := LoadPipelineConfig(*config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *checkpoint != "" {
+		cfg.Checkpoint = *checkpoint
+	}
+	if *signer != "" {
+		cfg.Signer = *signer
+	}
+	if *innerSigner != "" {
+		cfg.InnerSigner = *innerSigner
+	}
+	if flag.NArg() > 0 {
+		cfg.Input = cfg.Input[:0]
+		for _, value := range flag.Args() {
+			cfg.Input = append(cfg.Input, value)
+		}
+	}
+
+	if err := RunPipelineConfig(context.Background(), cfg, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets - верхние границы корзин гистограммы задержек
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+type stageMetrics struct {
+	in       int
+	out      int
+	maxDepth int
+	// моменты получения значений, которым ещё не нашлось пары на выходе
+	pending []time.Time
+	buckets []int
+	count   int
+	sum     time.Duration
+}
+
+// Metrics - встроенный PipelineObserver. Задержка звена считается от
+// получения самого старого ещё не вышедшего значения до очередного выхода:
+// для звеньев "одно на входе - одно на выходе" это и есть время обработки.
+// Глубина очереди - сколько значений звено получило, но ещё не отдало
+type Metrics struct {
+	mu           sync.Mutex
+	names        []string
+	stages       []*stageMetrics
+	overheatWait time.Duration
+	overheats    int
+}
+
+// NewMetrics создаёт Metrics; names - необязательные имена звеньев для отчётов
+func NewMetrics(names ...string) *Metrics {
+	return &Metrics{names: names}
+}
+
+func (m *Metrics) stage(stage int) *stageMetrics {
+	for len(m.stages) <= stage {
+		m.stages = append(m.stages, &stageMetrics{buckets: make([]int, len(latencyBuckets))})
+	}
+	return m.stages[stage]
+}
+
+func (m *Metrics) ItemIn(stage int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.stage(stage)
+	s.in++
+	s.pending = append(s.pending, time.Now())
+	if depth := s.in - s.out; depth > s.maxDepth {
+		s.maxDepth = depth
+	}
+}
+
+func (m *Metrics) ItemOut(stage int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.stage(stage)
+	s.out++
+	if len(s.pending) == 0 {
+		return
+	}
+
+	latency := time.Since(s.pending[0])
+	s.pending = s.pending[1:]
+	s.count++
+	s.sum += latency
+	for i, bound := range latencyBuckets {
+		if latency <= bound {
+			s.buckets[i]++
+		}
+	}
+}
+
+func (m *Metrics) OverheatWait(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.overheats++
+	m.overheatWait += d
+}
+
+func (m *Metrics) name(stage int) string {
+	if stage < len(m.names) {
+		return m.names[stage]
+	}
+	return strconv.Itoa(stage)
+}
+
+// WriteText печатает короткий отчёт по звеньям
+func (m *Metrics) WriteText(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, s := range m.stages {
+		depth := s.in - s.out
+		if depth < 0 {
+			depth = 0
+		}
+
+		var avg time.Duration
+		if s.count > 0 {
+			avg = s.sum / time.Duration(s.count)
+		}
+
+		_, err := fmt.Fprintf(w, "stage %s: in %d, out %d, queue %d (max %d), latency avg %s over %d\n",
+			m.name(i), s.in, s.out, depth, s.maxDepth, avg, s.count)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "overheat lock: %d waits, %s blocked\n", m.overheats, m.overheatWait)
+	return err
+}
+
+// WritePrometheus пишет метрики в текстовом формате Prometheus
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := &promWriter{w: w}
+
+	p.header("signer_stage_items_in_total", "counter", "Items received by the stage.")
+	for i, s := range m.stages {
+		p.sample("signer_stage_items_in_total", m.labels(i), strconv.Itoa(s.in))
+	}
+
+	p.header("signer_stage_items_out_total", "counter", "Items sent by the stage.")
+	for i, s := range m.stages {
+		p.sample("signer_stage_items_out_total", m.labels(i), strconv.Itoa(s.out))
+	}
+
+	p.header("signer_stage_queue_depth", "gauge", "Items received but not yet sent by the stage.")
+	for i, s := range m.stages {
+		p.sample("signer_stage_queue_depth", m.labels(i), strconv.Itoa(s.in-s.out))
+	}
+
+	p.header("signer_stage_queue_depth_max", "gauge", "Largest queue depth seen for the stage.")
+	for i, s := range m.stages {
+		p.sample("signer_stage_queue_depth_max", m.labels(i), strconv.Itoa(s.maxDepth))
+	}
+
+	p.header("signer_stage_latency_seconds", "histogram", "Time from receiving an item to sending a result.")
+	for i, s := range m.stages {
+		for b, bound := range latencyBuckets {
+			p.sample("signer_stage_latency_seconds_bucket", m.labels(i)+`,le="`+seconds(bound)+`"`, strconv.Itoa(s.buckets[b]))
+		}
+		p.sample("signer_stage_latency_seconds_bucket", m.labels(i)+`,le="+Inf"`, strconv.Itoa(s.count))
+		p.sample("signer_stage_latency_seconds_sum", m.labels(i), seconds(s.sum))
+		p.sample("signer_stage_latency_seconds_count", m.labels(i), strconv.Itoa(s.count))
+	}
+
+	p.header("signer_overheat_waits_total", "counter", "Times an md5 call had to wait for the signer.")
+	p.sample("signer_overheat_waits_total", "", strconv.Itoa(m.overheats))
+	p.header("signer_overheat_wait_seconds_total", "counter", "Time md5 calls spent waiting for the signer.")
+	p.sample("signer_overheat_wait_seconds_total", "", seconds(m.overheatWait))
+
+	return p.err
+}
+
+func (m *Metrics) labels(stage int) string {
+	return `stage="` + m.name(stage) + `"`
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
+
+// promWriter запоминает первую ошибку записи, чтобы не проверять каждую строку
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *promWriter) header(name, kind, help string) {
+	p.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (p *promWriter) sample(name, labels, value string) {
+	if labels != "" {
+		p.printf("%s{%s} %s\n", name, labels, value)
+		return
+	}
+	p.printf("%s %s\n", name, value)
+}
+
+func (p *promWriter) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
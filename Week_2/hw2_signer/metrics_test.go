@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsPipeline(t *testing.T) {
+	metrics := NewMetrics("source", "double", "sink")
+	var got []int
+
+	err := ExecutePipelineObserved(context.Background(), metrics,
+		withContext(func(in, out chan interface{}) {
+			for i := 0; i < 5; i++ {
+				out <- i
+			}
+		}),
+		withContext(func(in, out chan interface{}) {
+			for data := range in {
+				time.Sleep(time.Millisecond)
+				out <- data.(int) * 2
+			}
+		}),
+		withContext(func(in, out chan interface{}) {
+			for data := range in {
+				got = append(got, data.(int))
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 5 || got[4] != 8 {
+		t.Errorf("observer changed pipeline results: %v", got)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := metrics.WritePrometheus(buf); err != nil {
+		t.Fatal(err)
+	}
+	result := buf.String()
+	for _, expected := range []string{
+		`signer_stage_items_out_total{stage="source"} 5`,
+		`signer_stage_items_in_total{stage="double"} 5`,
+		`signer_stage_items_out_total{stage="double"} 5`,
+		`signer_stage_items_in_total{stage="sink"} 5`,
+		`signer_stage_queue_depth{stage="double"} 0`,
+		`signer_stage_latency_seconds_count{stage="double"} 5`,
+		`signer_stage_latency_seconds_bucket{stage="double",le="+Inf"} 5`,
+		"# TYPE signer_stage_latency_seconds histogram",
+		"signer_overheat_waits_total 0",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("prometheus output has no %q\nGot:\n%s", expected, result)
+		}
+	}
+
+	buf.Reset()
+	if err := metrics.WriteText(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "stage double: in 5, out 5, queue 0") {
+		t.Errorf("unexpected text report:\n%s", buf.String())
+	}
+}
+
+func TestMetricsOverheat(t *testing.T) {
+	crc32, md5 := DataSignerCrc32, DataSignerMd5
+	defer func() { DataSignerCrc32, DataSignerMd5 = crc32, md5 }()
+	DataSignerCrc32 = func(data string) string { return data }
+	DataSignerMd5 = func(data string) string {
+		time.Sleep(5 * time.Millisecond)
+		return data
+	}
+
+	// SingleHash считает все значения разом, так что md5 ждут друг друга
+	// в очереди Md5Limiter: в сумме около 5 + 10 + 15 + 20 мс
+	metrics := NewMetrics()
+	err := ExecutePipelineObserved(context.Background(), metrics,
+		withContext(func(in, out chan interface{}) {
+			for i := 0; i < 5; i++ {
+				out <- i
+			}
+		}),
+		stageContextJob(SingleHashStage, dataToString),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if metrics.overheats == 0 || metrics.overheatWait < 20*time.Millisecond {
+		t.Errorf("md5 waits were not observed: %d waits, %s", metrics.overheats, metrics.overheatWait)
+	}
+
+	if hook := Md5Limiter.SetWaitHook(nil); hook != nil {
+		t.Errorf("wait hook was not restored")
+	}
+}
+
+func TestMetricsConcurrentRuns(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- ExecutePipelineObserved(context.Background(), NewMetrics(),
+			withContext(func(in, out chan interface{}) {
+				<-release
+			}),
+		)
+	}()
+
+	// пока первый конвейер идёт, второй наблюдаемый не запускается,
+	// а без observer - запускается как обычно
+	var err error
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		if err = ExecutePipelineObserved(context.Background(), NewMetrics()); err != nil {
+			break
+		}
+	}
+	if err == nil || !strings.Contains(err.Error(), "another observed pipeline") {
+		t.Errorf("expected error for concurrent observed run, got %v", err)
+	}
+	if err := ExecutePipelineObserved(context.Background(), nil); err != nil {
+		t.Errorf("unexpected error without observer: %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ExecutePipelineObserved(context.Background(), NewMetrics()); err != nil {
+		t.Errorf("observer was not released: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// PipelineObserver получает события ExecutePipelineObserved. Методы
+// вызываются из разных горутин одновременно
+type PipelineObserver interface {
+	// ItemIn - звено stage получило значение
+	ItemIn(stage int)
+	// ItemOut - звено stage отдало значение
+	ItemOut(stage int)
+	// OverheatWait - сколько вызов md5 простоял в очереди к подписчику
+	OverheatWait(d time.Duration)
+}
+
+// relay стоит между звеньями stage и stage+1 и считает проходящие значения.
+// У последнего звена next == nil: его выход просто вычитывается
+func relay(observer PipelineObserver, stage int, out, next chan interface{}) {
+	if next != nil {
+		defer close(next)
+	}
+
+	for data := range out {
+		observer.ItemOut(stage)
+		if next == nil {
+			continue
+		}
+		next <- data
+		observer.ItemIn(stage + 1)
+	}
+}
+
+// observing - идёт ли сейчас наблюдаемый конвейер. Очередь Md5Limiter общая
+// на пакет, и ожидание в ней не приписать одному из двух конвейеров
+var observing int32
+
+// observeOverheat сообщает observer о каждом ожидании вызова md5 в очереди
+// Md5Limiter и возвращает функцию, которая вернёт всё как было. Если уже
+// наблюдается другой конвейер, возвращает ошибку
+func observeOverheat(observer PipelineObserver) (func(), error) {
+	if !atomic.CompareAndSwapInt32(&observing, 0, 1) {
+		return nil, fmt.Errorf("another observed pipeline is running")
+	}
+
+	md5Limiter := Md5Limiter
+	prev := md5Limiter.SetWaitHook(observer.OverheatWait)
+
+	return func() {
+		md5Limiter.SetWaitHook(prev)
+		atomic.StoreInt32(&observing, 0)
+	}, nil
+}
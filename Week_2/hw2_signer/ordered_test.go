@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParallelOrdered(t *testing.T) {
+	values := make([]int, 100)
+	for i := range values {
+		values[i] = i
+	}
+
+	for _, workers := range []int{0, 1, 4} {
+		stage := ParallelOrdered(workers, func(ctx context.Context, value int) (string, error) {
+			time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+			return strconv.Itoa(value), nil
+		})
+
+		for run := 0; run < 5; run++ {
+			results, err := RunStage(context.Background(), stage, values)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(results) != len(values) {
+				t.Fatalf("expected %d results, got %d", len(values), len(results))
+			}
+			for i, result := range results {
+				if result != strconv.Itoa(i) {
+					t.Fatalf("workers %d, run %d: result %d is %s", workers, run, i, result)
+				}
+			}
+		}
+	}
+}
+
+func TestParallelOrderedError(t *testing.T) {
+	errBad := errors.New("bad")
+	stage := ParallelOrdered(3, func(ctx context.Context, value int) (int, error) {
+		if value == 5 {
+			return 0, errBad
+		}
+		return value, nil
+	})
+
+	values := make([]int, 50)
+	for i := range values {
+		values[i] = i
+	}
+
+	results, err := RunStage(context.Background(), stage, values)
+	if !errors.Is(err, errBad) {
+		t.Errorf("expected errBad, got %v", err)
+	}
+	for i, result := range results {
+		if result != i {
+			t.Errorf("results before the error must stay ordered: %v", results)
+			break
+		}
+	}
+}
+
+func TestSingleHashOrdered(t *testing.T) {
+	trackCrc32(t)
+
+	values := []string{"0", "1", "1", "2", "3", "5", "8"}
+	stage := Chain(SingleHashStageWith(StageOptions{Ordered: true}), MultiHashStageWith(StageOptions{Ordered: true, Workers: 3}))
+
+	expected, err := RunStage(context.Background(), stage, values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for run := 0; run < 3; run++ {
+		results, err := RunStage(context.Background(), stage, values)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i := range expected {
+			if results[i] != expected[i] {
+				t.Fatalf("run %d: order differs at %d", run, i)
+			}
+		}
+	}
+	for i, value := range values {
+		// подменённый crc32 возвращает вход, так что результат начинается с числа
+		if expected[i][1:1+len(value)] != value {
+			t.Errorf("result %d does not belong to input %s: %s", i, value, expected[i])
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// contextJob - звено конвейера, которое знает о контексте и может вернуть ошибку
+type contextJob func(ctx context.Context, in, out chan interface{}) error
+
+// ExecutePipelineContext выполняет конвейер как ExecutePipeline, но первая же
+// ошибка (или паника) в любом звене отменяет контекст всех остальных и
+// возвращается наружу. Каналы при этом всё равно дочитываются и закрываются,
+// так что ни одна горутина не остаётся висеть на отправке
+func ExecutePipelineContext(ctx context.Context, jobs ...contextJob) error {
+	return ExecutePipelineObserved(ctx, nil, jobs...)
+}
+
+// ExecutePipelineObserved - ExecutePipelineContext, который сообщает observer
+// о прохождении значений между звеньями; observer может быть nil. Наблюдать
+// одновременно можно только один конвейер, второй сразу получает ошибку
+func ExecutePipelineObserved(ctx context.Context, observer PipelineObserver, jobs ...contextJob) error {
+	if observer != nil {
+		restore, err := observeOverheat(observer)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+	once := &sync.Once{}
+	var firstErr error
+
+	in := make(chan interface{})
+	close(in)
+	for i, j := range jobs {
+		out := make(chan interface{})
+		wg.Add(1)
+		go func(i int, j contextJob, in, out chan interface{}) {
+			defer wg.Done()
+			if err := runContextJob(ctx, j, in, out); err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("stage %d: %w", i, err)
+					cancel()
+				})
+			}
+			// звено могло выйти, не дочитав вход: освобождаем предыдущее звено.
+			// Отмена выше должна случиться раньше, иначе предыдущее звено
+			// так и будет слать данные в этот drain
+			drain(in)
+		}(i, j, in, out)
+
+		if observer != nil {
+			var next chan interface{}
+			if i+1 < len(jobs) {
+				next = make(chan interface{})
+			}
+			wg.Add(1)
+			go func(i int, out, next chan interface{}) {
+				defer wg.Done()
+				relay(observer, i, out, next)
+			}(i, out, next)
+			out = next
+		}
+		in = out
+	}
+
+	// выход последнего звена никто не читает - вычитываем его сами
+	if in != nil {
+		go drain(in)
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+func runContextJob(ctx context.Context, j contextJob, in, out chan interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		close(out)
+	}()
+
+	return j(ctx, in, out)
+}
+
+func drain(in chan interface{}) {
+	for range in {
+	}
+}
+
+// withContext превращает обычный job в contextJob; такой job про отмену
+// не знает, поэтому останавливается только когда закончится его вход
+func withContext(j job) contextJob {
+	return func(ctx context.Context, in, out chan interface{}) error {
+		j(in, out)
+		return nil
+	}
+}
+
+// sendContext отправляет значение, пока контекст не отменён
+func sendContext(ctx context.Context, out chan interface{}, value interface{}) error {
+	select {
+	case out <- value:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
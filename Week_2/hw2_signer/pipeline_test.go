@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPipelineContextError(t *testing.T) {
+	errStop := errors.New("stop")
+	var sent uint32
+
+	start := time.Now()
+	err := ExecutePipelineContext(context.Background(),
+		func(ctx context.Context, in, out chan interface{}) error {
+			for i := 0; ; i++ {
+				if err := sendContext(ctx, out, i); err != nil {
+					return err
+				}
+				atomic.AddUint32(&sent, 1)
+			}
+		},
+		func(ctx context.Context, in, out chan interface{}) error {
+			for data := range in {
+				if data.(int) == 3 {
+					return errStop
+				}
+				out <- data
+			}
+			return nil
+		},
+		func(ctx context.Context, in, out chan interface{}) error {
+			for range in {
+			}
+			return nil
+		},
+	)
+
+	if !errors.Is(err, errStop) {
+		t.Errorf("expected errStop, got %v", err)
+	}
+	if end := time.Since(start); end > time.Second {
+		t.Errorf("pipeline was not cancelled, took %s", end)
+	}
+	if atomic.LoadUint32(&sent) < 3 {
+		t.Errorf("values were not sent before error")
+	}
+}
+
+func TestPipelineContextPanic(t *testing.T) {
+	err := ExecutePipelineContext(context.Background(),
+		withContext(func(in, out chan interface{}) {
+			for i := 0; i < 100; i++ {
+				out <- i
+			}
+		}),
+		withContext(func(in, out chan interface{}) {
+			for data := range in {
+				_ = data.(string)
+			}
+		}),
+	)
+
+	if err == nil || !strings.Contains(err.Error(), "stage 1: panic") {
+		t.Errorf("expected recovered panic, got %v", err)
+	}
+}
+
+func TestPipelineContextCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := ExecutePipelineContext(ctx,
+		func(ctx context.Context, in, out chan interface{}) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		withContext(func(in, out chan interface{}) {
+			for range in {
+			}
+		}),
+	)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+}
+
+func TestExecutePipelinePanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic from ExecutePipeline")
+		}
+	}()
+
+	ExecutePipeline(job(func(in, out chan interface{}) {
+		panic("boom")
+	}))
+}
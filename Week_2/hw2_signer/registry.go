@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StageFactory собирает звено по его описанию из конфига
+type StageFactory func(cfg StageConfig) (contextJob, error)
+
+var (
+	registryMu    sync.RWMutex
+	stageRegistry = make(map[string]StageFactory)
+)
+
+// RegisterStage добавляет звено в реестр под именем name. Повторная
+// регистрация имени - ошибка программиста, поэтому паника
+func RegisterStage(name string, factory StageFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := stageRegistry[name]; ok {
+		panic("signer: stage " + name + " registered twice")
+	}
+	stageRegistry[name] = factory
+}
+
+// lookupStage ищет звено в реестре
+func lookupStage(name string) (StageFactory, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, ok := stageRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown stage %q, available: %s", name, strings.Join(registeredStages(), ", "))
+	}
+	return factory, nil
+}
+
+// registeredStages возвращает отсортированные имена звеньев; вызывается под registryMu
+func registeredStages() []string {
+	names := make([]string, 0, len(stageRegistry))
+	for name := range stageRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterStage("single_hash", func(cfg StageConfig) (contextJob, error) {
+		return stageContextJob(SingleHashStageWith(cfg.options()), dataToString), nil
+	})
+	RegisterStage("multi_hash", func(cfg StageConfig) (contextJob, error) {
+		return stageContextJob(MultiHashStageWith(cfg.options()), dataToString), nil
+	})
+	RegisterStage("combine", func(cfg StageConfig) (contextJob, error) {
+		return stageContextJob(Buffered(CombineResultsStage, cfg.Buffer), dataToString), nil
+	})
+	RegisterStage("filter", filterStage)
+	RegisterStage("map", mapStage)
+	RegisterStage("batch", batchStage)
+}
+
+// filterStage пропускает значения, подходящие под все заданные условия:
+// contains, prefix, suffix, match (регулярное выражение); not: true
+// переворачивает результат
+func filterStage(cfg StageConfig) (contextJob, error) {
+	contains, hasContains := cfg.param("contains")
+	prefix := cfg.paramDefault("prefix", "")
+	suffix := cfg.paramDefault("suffix", "")
+	not, err := cfg.paramBool("not")
+	if err != nil {
+		return nil, err
+	}
+
+	var re *regexp.Regexp
+	if expr, ok := cfg.param("match"); ok {
+		if re, err = regexp.Compile(expr); err != nil {
+			return nil, fmt.Errorf("filter: bad match: %w", err)
+		}
+	}
+
+	keep := func(data string) bool {
+		ok := strings.HasPrefix(data, prefix) && strings.HasSuffix(data, suffix) &&
+			(!hasContains || strings.Contains(data, contains)) &&
+			(re == nil || re.MatchString(data))
+		return ok != not
+	}
+
+	return stageContextJob(Buffered(func(ctx context.Context, in <-chan string, out chan<- string) error {
+		for data := range in {
+			if !keep(data) {
+				continue
+			}
+			if err := sendTyped(ctx, out, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, cfg.Buffer), dataToString), nil
+}
+
+// mapStage меняет каждое значение: case (upper или lower), затем
+// дописывает prefix и suffix
+func mapStage(cfg StageConfig) (contextJob, error) {
+	prefix := cfg.paramDefault("prefix", "")
+	suffix := cfg.paramDefault("suffix", "")
+
+	var convert func(string) string
+	switch mode := cfg.paramDefault("case", ""); mode {
+	case "":
+		convert = func(data string) string { return data }
+	case "upper":
+		convert = strings.ToUpper
+	case "lower":
+		convert = strings.ToLower
+	default:
+		return nil, fmt.Errorf("map: unknown case %q", mode)
+	}
+
+	return stageContextJob(Buffered(parallel(cfg.options(), func(ctx context.Context, data string) (string, error) {
+		return prefix + convert(data) + suffix, nil
+	}), cfg.Buffer), dataToString), nil
+}
+
+// batchStage склеивает по size подряд идущих значений через sep ("_" по
+// умолчанию); неполная последняя пачка тоже отдаётся
+func batchStage(cfg StageConfig) (contextJob, error) {
+	size, err := strconv.Atoi(cfg.paramDefault("size", ""))
+	if err != nil || size < 1 {
+		return nil, fmt.Errorf("batch: size must be a positive number")
+	}
+	sep := cfg.paramDefault("sep", "_")
+
+	return stageContextJob(Buffered(func(ctx context.Context, in <-chan string, out chan<- string) error {
+		batch := make([]string, 0, size)
+		for data := range in {
+			batch = append(batch, data)
+			if len(batch) < size {
+				continue
+			}
+			if err := sendTyped(ctx, out, strings.Join(batch, sep)); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+		if len(batch) > 0 {
+			return sendTyped(ctx, out, strings.Join(batch, sep))
+		}
+		return nil
+	}, cfg.Buffer), dataToString), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func runConfig(t *testing.T, config string) string {
+	t.Helper()
+	cfg, err := ParsePipelineConfig([]byte(config), "yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := &bytes.Buffer{}
+	if err := RunPipelineConfig(context.Background(), cfg, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return out.String()
+}
+
+func TestRegistryCustomStages(t *testing.T) {
+	result := runConfig(t, `
+input: [apple, banana, cherry, avocado, blueberry]
+stages:
+  - name: filter
+    params:
+      match: ^[ab]
+  - name: filter
+    params:
+      contains: an
+      not: true
+  - name: map
+    ordered: true
+    workers: 2
+    params:
+      case: upper
+      suffix: "!"
+  - name: batch
+    params:
+      size: 2
+      sep: " + "
+`)
+
+	expected := "APPLE! + AVOCADO!\nBLUEBERRY!\n"
+	if result != expected {
+		t.Errorf("results not match\nGot:\n%s\nExpected:\n%s", result, expected)
+	}
+}
+
+func TestRegisterStage(t *testing.T) {
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(stageRegistry, "test_reverse")
+		registryMu.Unlock()
+	})
+	RegisterStage("test_reverse", func(cfg StageConfig) (contextJob, error) {
+		return stageContextJob(Parallel(0, func(ctx context.Context, data string) (string, error) {
+			runes := []rune(data)
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			return string(runes), nil
+		}), dataToString), nil
+	})
+
+	if result := runConfig(t, "input: [abc]\nstages:\n  - name: test_reverse\n"); result != "cba\n" {
+		t.Errorf("custom stage was not used: %q", result)
+	}
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "registered twice") {
+			t.Errorf("expected panic on duplicate stage, got %v", r)
+		}
+	}()
+	RegisterStage("test_reverse", nil)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
@@ -10,100 +12,179 @@ import (
 // SingleHash считает значение crc32(data)+"~"+crc32(md5(data))
 // ( конкатенация двух строк через ~), где data - то что пришло на вход (по сути - числа из первой функции)
 func SingleHash(in, out chan interface{}) {
-	wg := &sync.WaitGroup{}
-	mu := &sync.Mutex{}
-
-	for data := range in {
-		value, ok := data.(string)
-		if !ok {
-			value = strconv.Itoa(data.(int))
-		}
-
-		wg.Add(1)
-		go func(data string) {
-			defer wg.Done()
-			dataHash1 := make(chan string)
-			dataHash2 := make(chan string)
-
-			go func() {
-				dataHash1 <- DataSignerCrc32(data)
-			}()
-
-			go func() {
-				mu.Lock()
-				md5hash := DataSignerMd5(data)
-				mu.Unlock()
-				dataHash2 <- DataSignerCrc32(md5hash)
-			}()
-
-			out <- <-dataHash1 + "~" + <-dataHash2
-		}(value)
-	}
+	stageJob(SingleHashStage, dataToString)(in, out)
+}
 
-	wg.Wait()
+// SingleHashWith - SingleHash с ограничениями opts для ExecutePipeline
+func SingleHashWith(opts StageOptions) job {
+	return stageJob(SingleHashStageWith(opts), dataToString)
 }
 
 // MultiHash считает значение crc32(th+data)) (конкатенация цифры, приведённой к строке и строки), где th=0..5 ( т.е. 6 хешей на каждое входящее значение ),
 // потом берёт конкатенацию результатов в порядке расчета (0..5), где data - то что пришло на вход (и ушло на выход из SingleHash)
 func MultiHash(in, out chan interface{}) {
-	wg := &sync.WaitGroup{}
-
-	for data := range in {
-		value, ok := data.(string)
-		if !ok {
-			value = strconv.Itoa(data.(int))
-		}
-
-		wg.Add(1)
-		go func(data string) {
-			defer wg.Done()
-			workers := &sync.WaitGroup{}
-			dataHashes := make([]string, 6)
-			for th := 0; th < 6; th++ {
-				workers.Add(1)
-				go func(th int) {
-					defer workers.Done()
-					dataHashes[th] = DataSignerCrc32(strconv.Itoa(th) + data)
-				}(th)
-			}
-			workers.Wait()
-			out <- strings.Join(dataHashes, "")
-		}(value)
-	}
+	stageJob(MultiHashStage, dataToString)(in, out)
+}
 
-	wg.Wait()
+// MultiHashWith - MultiHash с ограничениями opts для ExecutePipeline
+func MultiHashWith(opts StageOptions) job {
+	return stageJob(MultiHashStageWith(opts), dataToString)
 }
 
 // CombineResults получает все результаты,
 // сортирует (https://golang.org/pkg/sort/), объединяет отсортированный результат через _ (символ подчеркивания) в одну строку
 //
 func CombineResults(in, out chan interface{}) {
+	stageJob(CombineResultsStage, dataToString)(in, out)
+}
+
+// dataToString приводит пришедшие в job данные к строке: на вход SingleHash
+// приходят числа, дальше по конвейеру - строки
+func dataToString(data interface{}) (string, error) {
+	switch value := data.(type) {
+	case string:
+		return value, nil
+	case int:
+		return strconv.Itoa(value), nil
+	}
+	return "", fmt.Errorf("unexpected data %v (%T)", data, data)
+}
+
+// StageOptions ограничивают звено: Workers - сколько значений считается
+// одновременно (0 - без ограничения), Buffer - размер буфера на выходе,
+// Ordered - отдавать результаты в порядке входа, а не по готовности,
+// Checkpoint - журнал, из которого берутся уже посчитанные значения,
+// Signer и InnerSigner - алгоритмы вместо crc32 и md5 (nil - они и есть)
+type StageOptions struct {
+	Workers     int
+	Buffer      int
+	Ordered     bool
+	Checkpoint  *Checkpoint
+	Signer      Signer
+	InnerSigner Signer
+}
+
+// signers возвращает алгоритмы из opts с подставленными по умолчанию
+func (opts StageOptions) signers() (signer, inner Signer) {
+	signer, inner = opts.Signer, opts.InnerSigner
+	if signer == nil {
+		signer = Crc32Signer{}
+	}
+	if inner == nil {
+		inner = Md5Signer{}
+	}
+	return signer, inner
+}
+
+// parallel выбирает Parallel или ParallelOrdered по opts
+func parallel[In, Out any](opts StageOptions, fn func(ctx context.Context, value In) (Out, error)) Stage[In, Out] {
+	if opts.Ordered {
+		return ParallelOrdered(opts.Workers, fn)
+	}
+	return Parallel(opts.Workers, fn)
+}
+
+// SingleHashStage - типизированная версия SingleHash
+func SingleHashStage(ctx context.Context, in <-chan string, out chan<- string) error {
+	return SingleHashStageWith(StageOptions{})(ctx, in, out)
+}
+
+// SingleHashStageWith - SingleHashStage с ограничениями opts
+func SingleHashStageWith(opts StageOptions) Stage[string, string] {
+	signer, inner := opts.signers()
+	// результат зависит от алгоритмов, так что и запись в журнале тоже
+	name := "single_hash/" + signer.Name() + "/" + inner.Name()
+	return Buffered(parallel(opts, checkpointed(opts.Checkpoint, name, func(ctx context.Context, data string) (string, error) {
+		return singleHash(ctx, data, signer, inner)
+	})), opts.Buffer)
+}
+
+// singleHash считает signer(data)+"~"+signer(inner(data))
+func singleHash(ctx context.Context, data string, signer, inner Signer) (string, error) {
+	type result struct {
+		hash string
+		err  error
+	}
+	dataHash1 := make(chan result, 1)
+
+	go func() {
+		hash, err := signer.Sign(ctx, data)
+		dataHash1 <- result{hash, err}
+	}()
+
+	innerHash, err := inner.Sign(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	dataHash2, err := signer.Sign(ctx, innerHash)
+	if err != nil {
+		return "", err
+	}
+
+	first := <-dataHash1
+	if first.err != nil {
+		return "", first.err
+	}
+	return first.hash + "~" + dataHash2, nil
+}
+
+// MultiHashStage - типизированная версия MultiHash
+func MultiHashStage(ctx context.Context, in <-chan string, out chan<- string) error {
+	return MultiHashStageWith(StageOptions{})(ctx, in, out)
+}
+
+// MultiHashStageWith - MultiHashStage с ограничениями opts; каждое значение
+// всё равно считается в 6 горутин, лимит касается числа значений
+func MultiHashStageWith(opts StageOptions) Stage[string, string] {
+	signer, _ := opts.signers()
+	name := "multi_hash/" + signer.Name()
+	return Buffered(parallel(opts, checkpointed(opts.Checkpoint, name, func(ctx context.Context, data string) (string, error) {
+		return multiHash(ctx, data, signer)
+	})), opts.Buffer)
+}
+
+// multiHash считает signer(th+data) для th=0..5 и склеивает по порядку th
+func multiHash(ctx context.Context, data string, signer Signer) (string, error) {
+	workers := &sync.WaitGroup{}
+	dataHashes := make([]string, 6)
+	errs := make([]error, 6)
+	for th := 0; th < 6; th++ {
+		workers.Add(1)
+		go func(th int) {
+			defer workers.Done()
+			dataHashes[th], errs[th] = signer.Sign(ctx, strconv.Itoa(th)+data)
+		}(th)
+	}
+	workers.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(dataHashes, ""), nil
+}
+
+// CombineResultsStage - типизированная версия CombineResults
+func CombineResultsStage(ctx context.Context, in <-chan string, out chan<- string) error {
 	results := make([]string, 0, 5)
 	for data := range in {
-		results = append(results, data.(string))
+		results = append(results, data)
 	}
 	sort.Strings(results)
-	result := strings.Join(results, "_")
-	out <- result
+	return sendTyped(ctx, out, strings.Join(results, "_"))
 }
 
 // ExecutePipeline выполняет массив всех пришедших функций
 func ExecutePipeline(jobs ...job) {
-	wg := &sync.WaitGroup{}
-	in := make(chan interface{})
-	out := make(chan interface{})
+	contextJobs := make([]contextJob, 0, len(jobs))
 	for _, j := range jobs {
-		wg.Add(1)
-		go jobWorker(wg, j, in, out)
-		in = out
-		out = make(chan interface{})
+		contextJobs = append(contextJobs, withContext(j))
 	}
-	wg.Wait()
-	close(out)
-}
 
-func jobWorker(wg *sync.WaitGroup, j job, in, out chan interface{}) {
-	defer wg.Done()
-	j(in, out)
-	close(out)
+	// у обычных job нет способа вернуть ошибку, так что паника звена
+	// поднимается дальше, как и раньше, но уже без зависших горутин
+	if err := ExecutePipelineContext(context.Background(), contextJobs...); err != nil {
+		panic(err)
+	}
 }
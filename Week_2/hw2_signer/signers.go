@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Signer - алгоритм подписи для SingleHash и MultiHash. Соль DataSignerSalt
+// каждый алгоритм учитывает сам
+type Signer interface {
+	// Name - имя алгоритма для конфигов и журнала
+	Name() string
+	Sign(ctx context.Context, data string) (string, error)
+}
+
+type (
+	// Crc32Signer - DataSignerCrc32 (вместе с его задержкой)
+	Crc32Signer struct{}
+	// Md5Signer - DataSignerMd5 в очереди Md5Limiter
+	Md5Signer struct{}
+	// Sha256Signer - sha256(data+соль) в hex
+	Sha256Signer struct{}
+	// XXHashSigner - xxh64(data+соль) десятичным числом, как crc32
+	XXHashSigner struct{}
+	// HMACSigner - HMAC-SHA256 от data с ключом DataSignerSalt в hex
+	HMACSigner struct{}
+)
+
+func (Crc32Signer) Name() string { return "crc32" }
+
+func (Crc32Signer) Sign(ctx context.Context, data string) (string, error) {
+	return DataSignerCrc32(data), nil
+}
+
+func (Md5Signer) Name() string { return "md5" }
+
+func (Md5Signer) Sign(ctx context.Context, data string) (string, error) {
+	return SignMd5(ctx, data)
+}
+
+func (Sha256Signer) Name() string { return "sha256" }
+
+func (Sha256Signer) Sign(ctx context.Context, data string) (string, error) {
+	sum := sha256.Sum256([]byte(data + DataSignerSalt))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (XXHashSigner) Name() string { return "xxhash" }
+
+func (XXHashSigner) Sign(ctx context.Context, data string) (string, error) {
+	return strconv.FormatUint(xxhash64([]byte(data+DataSignerSalt)), 10), nil
+}
+
+func (HMACSigner) Name() string { return "hmac" }
+
+func (HMACSigner) Sign(ctx context.Context, data string) (string, error) {
+	mac := hmac.New(sha256.New, []byte(DataSignerSalt))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+var signers = map[string]Signer{}
+
+func init() {
+	for _, s := range []Signer{Crc32Signer{}, Md5Signer{}, Sha256Signer{}, XXHashSigner{}, HMACSigner{}} {
+		signers[s.Name()] = s
+	}
+}
+
+// SignerByName возвращает встроенный алгоритм по имени
+func SignerByName(name string) (Signer, error) {
+	s, ok := signers[name]
+	if !ok {
+		names := make([]string, 0, len(signers))
+		for n := range signers {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown signer %q, available: %s", name, strings.Join(names, ", "))
+	}
+	return s, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestXXHash64(t *testing.T) {
+	cases := []struct {
+		data string
+		hash uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		{"hello, world", 0xb33a384e6d1b1242},
+		{"abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789$", 0x1032d841e824f998},
+	}
+
+	for _, c := range cases {
+		if got := xxhash64([]byte(c.data)); got != c.hash {
+			t.Errorf("xxhash64(%q) = %#x, expected %#x", c.data, got, c.hash)
+		}
+	}
+}
+
+func TestSigners(t *testing.T) {
+	salt := DataSignerSalt
+	defer func() { DataSignerSalt = salt }()
+
+	cases := []struct {
+		name, salt, data, expected string
+	}{
+		{"sha256", "", "0", "5feceb66ffc86f38d952786c6d696c79c2dbc239dd4e91b46729d73a27fb57e9"},
+		{"xxhash", "", "abc", strconv.FormatUint(0x44bc2cf5ad770999, 10)},
+		{"hmac", "key", "0", "089c386a9149b5cce5972bfe0f05c8d6e92de22e902457b3a23a69a79f85fa97"},
+	}
+
+	for _, c := range cases {
+		DataSignerSalt = c.salt
+		signer, err := SignerByName(c.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := signer.Sign(context.Background(), c.data); err != nil || got != c.expected {
+			t.Errorf("%s(%q) = %q, %v, expected %q", c.name, c.data, got, err, c.expected)
+		}
+	}
+
+	if _, err := SignerByName("sha1"); err == nil || !strings.Contains(err.Error(), "crc32, hmac, md5, sha256, xxhash") {
+		t.Errorf("expected unknown signer error, got %v", err)
+	}
+}
+
+func TestStageSigners(t *testing.T) {
+	sha := func(data string) string {
+		sum := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(sum[:])
+	}
+	xx := func(data string) string {
+		return strconv.FormatUint(xxhash64([]byte(data)), 10)
+	}
+
+	opts := StageOptions{Signer: Sha256Signer{}, InnerSigner: XXHashSigner{}}
+	signer := Chain(Chain(SingleHashStageWith(opts), MultiHashStageWith(opts)), CombineResultsStage)
+	results, err := RunStage(context.Background(), signer, []string{"7"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	single := sha("7") + "~" + sha(xx("7"))
+	expected := ""
+	for th := 0; th < 6; th++ {
+		expected += sha(strconv.Itoa(th) + single)
+	}
+	if len(results) != 1 || results[0] != expected {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", results, expected)
+	}
+}
+
+func TestPipelineConfigSignerNames(t *testing.T) {
+	result := runConfig(t, "input: [1]\nsigner: xxhash\ninner_signer: sha256\nstages:\n  - name: single_hash\n")
+
+	sum := sha256.Sum256([]byte("1"))
+	expected := strconv.FormatUint(xxhash64([]byte("1")), 10) + "~" +
+		strconv.FormatUint(xxhash64([]byte(hex.EncodeToString(sum[:]))), 10) + "\n"
+	if result != expected {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", result, expected)
+	}
+
+	cfg, _ := ParsePipelineConfig([]byte(`{"signer": "sha1", "stages": [{"name": "multi_hash"}]}`), "json")
+	if _, err := cfg.Build(); err == nil || !strings.Contains(err.Error(), `unknown signer "sha1"`) {
+		t.Errorf("expected unknown signer error, got %v", err)
+	}
+}
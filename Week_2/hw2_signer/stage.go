@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Stage - типизированное звено конвейера. Звено читает in, пока тот не закроют
+// (или пока не отменят ctx), и пишет в out; закрывает out тот, кто звено запустил
+type Stage[In, Out any] func(ctx context.Context, in <-chan In, out chan<- Out) error
+
+// Chain соединяет два звена в одно. Типы проверяются при компиляции:
+// выход first обязан совпадать со входом second
+func Chain[A, B, C any](first Stage[A, B], second Stage[B, C]) Stage[A, C] {
+	return func(ctx context.Context, in <-chan A, out chan<- C) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		middle := make(chan B)
+		firstErr := make(chan error, 1)
+		go func() {
+			err := runStage(ctx, first, in, middle)
+			close(middle)
+			if err != nil {
+				cancel()
+			}
+			firstErr <- err
+		}()
+
+		err := runStage(ctx, second, middle, out)
+		if err != nil {
+			cancel()
+		}
+		// second мог выйти раньше времени - не даём first зависнуть на отправке
+		drainChan(middle)
+
+		if errFirst := <-firstErr; errFirst != nil && (err == nil || err == context.Canceled) {
+			return errFirst
+		}
+		return err
+	}
+}
+
+// Parallel обрабатывает значения независимо друг от друга, не больше workers
+// одновременно; workers <= 0 - горутина на каждое значение. Пока все воркеры
+// заняты, вход не читается, и предыдущее звено упирается в отправку -
+// так ограничение работает как backpressure. Результаты идут в порядке готовности
+func Parallel[In, Out any](workers int, fn func(ctx context.Context, value In) (Out, error)) Stage[In, Out] {
+	return func(ctx context.Context, in <-chan In, out chan<- Out) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var sem chan struct{}
+		if workers > 0 {
+			sem = make(chan struct{}, workers)
+		}
+
+		wg := &sync.WaitGroup{}
+		once := &sync.Once{}
+		var firstErr error
+		fail := func(err error) {
+			once.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+
+	loop:
+		for value := range in {
+			// без ограничения воркеров select ниже не случается - после
+			// ошибки не запускаем fn на всё, что ещё приходит на вход
+			if ctx.Err() != nil {
+				break
+			}
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					break loop
+				}
+			}
+
+			wg.Add(1)
+			go func(value In) {
+				defer wg.Done()
+				if sem != nil {
+					defer func() { <-sem }()
+				}
+				defer func() {
+					if r := recover(); r != nil {
+						fail(fmt.Errorf("panic: %v", r))
+					}
+				}()
+
+				result, err := fn(ctx, value)
+				if err != nil {
+					fail(err)
+					return
+				}
+				sendTyped(ctx, out, result)
+			}(value)
+		}
+		wg.Wait()
+
+		if firstErr != nil {
+			return firstErr
+		}
+		return ctx.Err()
+	}
+}
+
+// ParallelOrdered - как Parallel, но результаты выходят в порядке входа.
+// Каждому значению присваивается номер, готовые результаты ждут в буфере,
+// пока не выйдут все предыдущие. Воркер освобождается только когда его
+// результат отправлен дальше, поэтому в буфере не больше workers значений
+func ParallelOrdered[In, Out any](workers int, fn func(ctx context.Context, value In) (Out, error)) Stage[In, Out] {
+	type result struct {
+		seq   int
+		value Out
+	}
+
+	return func(ctx context.Context, in <-chan In, out chan<- Out) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var sem chan struct{}
+		if workers > 0 {
+			sem = make(chan struct{}, workers)
+		}
+
+		once := &sync.Once{}
+		var firstErr error
+		fail := func(err error) {
+			once.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+
+		results := make(chan result)
+		reordered := make(chan struct{})
+		go func() {
+			defer close(reordered)
+			pending := map[int]Out{}
+			next := 0
+			for r := range results {
+				pending[r.seq] = r.value
+				for {
+					value, ok := pending[next]
+					if !ok {
+						break
+					}
+					delete(pending, next)
+					next++
+					// после отмены результаты только вычитываются
+					if ctx.Err() == nil {
+						sendTyped(ctx, out, value)
+					}
+					if sem != nil {
+						<-sem
+					}
+				}
+			}
+		}()
+
+		wg := &sync.WaitGroup{}
+		seq := 0
+	loop:
+		for value := range in {
+			// без ограничения воркеров select ниже не случается - после
+			// ошибки не запускаем fn на всё, что ещё приходит на вход
+			if ctx.Err() != nil {
+				break
+			}
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					break loop
+				}
+			}
+
+			wg.Add(1)
+			go func(seq int, value In) {
+				defer wg.Done()
+				defer func() {
+					if r := recover(); r != nil {
+						fail(fmt.Errorf("panic: %v", r))
+					}
+				}()
+
+				converted, err := fn(ctx, value)
+				if err != nil {
+					fail(err)
+					return
+				}
+				results <- result{seq: seq, value: converted}
+			}(seq, value)
+			seq++
+		}
+		wg.Wait()
+		close(results)
+		<-reordered
+
+		if firstErr != nil {
+			return firstErr
+		}
+		return ctx.Err()
+	}
+}
+
+// Buffered ставит на выходе звена буфер на size значений, чтобы медленный
+// потребитель не сразу останавливал звено
+func Buffered[In, Out any](stage Stage[In, Out], size int) Stage[In, Out] {
+	if size <= 0 {
+		return stage
+	}
+
+	return func(ctx context.Context, in <-chan In, out chan<- Out) error {
+		buffer := make(chan Out, size)
+		stageErr := make(chan error, 1)
+		go func() {
+			err := runStage(ctx, stage, in, buffer)
+			close(buffer)
+			stageErr <- err
+		}()
+
+		for result := range buffer {
+			out <- result
+		}
+		return <-stageErr
+	}
+}
+
+// RunStage прогоняет через звено срез значений и собирает результат
+func RunStage[In, Out any](ctx context.Context, stage Stage[In, Out], values []In) ([]Out, error) {
+	in := make(chan In)
+	out := make(chan Out)
+
+	go func() {
+		defer close(in)
+		for _, value := range values {
+			select {
+			case in <- value:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	stageErr := make(chan error, 1)
+	go func() {
+		err := runStage(ctx, stage, in, out)
+		close(out)
+		drainChan(in)
+		stageErr <- err
+	}()
+
+	var results []Out
+	for result := range out {
+		results = append(results, result)
+	}
+
+	return results, <-stageErr
+}
+
+func runStage[In, Out any](ctx context.Context, stage Stage[In, Out], in <-chan In, out chan<- Out) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return stage(ctx, in, out)
+}
+
+func drainChan[T any](in <-chan T) {
+	for range in {
+	}
+}
+
+func sendTyped[T any](ctx context.Context, out chan<- T, value T) error {
+	select {
+	case out <- value:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stageContextJob позволяет поставить типизированное звено в ExecutePipelineContext:
+// входные значения приводятся через convert, ошибка приведения останавливает конвейер
+func stageContextJob[In, Out any](stage Stage[In, Out], convert func(interface{}) (In, error)) contextJob {
+	return func(ctx context.Context, in, out chan interface{}) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		typedIn := make(chan In)
+		typedOut := make(chan Out)
+		convertErr := make(chan error, 1)
+
+		go func() {
+			defer close(typedIn)
+			for {
+				var data interface{}
+				select {
+				case value, ok := <-in:
+					if !ok {
+						return
+					}
+					data = value
+				case <-ctx.Done():
+					// остаток входа дочитает drain в ExecutePipelineContext
+					return
+				}
+
+				value, err := convert(data)
+				if err != nil {
+					// ошибку кладём до отмены: звено, остановленное отменой,
+					// не должно успеть вернуть context.Canceled раньше неё
+					convertErr <- err
+					cancel()
+					return
+				}
+				if sendTyped(ctx, typedIn, value) != nil {
+					return
+				}
+			}
+		}()
+
+		stageErr := make(chan error, 1)
+		go func() {
+			err := runStage(ctx, stage, typedIn, typedOut)
+			close(typedOut)
+			// звено закончило - конвертер больше не нужен. Без отмены он
+			// продолжал бы читать бесконечный вход и отдавать его в drainChan,
+			// а внешний конвейер отменит предыдущие звенья только после нашего выхода
+			cancel()
+			drainChan(typedIn)
+			stageErr <- err
+		}()
+
+		for result := range typedOut {
+			out <- result
+		}
+
+		err := <-stageErr
+		select {
+		case errConvert := <-convertErr:
+			return errConvert
+		default:
+			return err
+		}
+	}
+}
+
+// stageJob - то же для старого ExecutePipeline: job не умеет возвращать
+// ошибки, поэтому они превращаются в панику
+func stageJob[In, Out any](stage Stage[In, Out], convert func(interface{}) (In, error)) job {
+	contextJob := stageContextJob(stage, convert)
+	return func(in, out chan interface{}) {
+		if err := contextJob(context.Background(), in, out); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// JobStage - обратный адаптер: старый job внутри типизированной цепочки
+func JobStage(j job) Stage[interface{}, interface{}] {
+	return func(ctx context.Context, in <-chan interface{}, out chan<- interface{}) error {
+		jobIn := make(chan interface{})
+		jobOut := make(chan interface{})
+
+		go func() {
+			defer close(jobIn)
+			for data := range in {
+				jobIn <- data
+			}
+		}()
+
+		jobErr := make(chan error, 1)
+		go func() {
+			defer close(jobOut)
+			jobErr <- runJob(j, jobIn, jobOut)
+		}()
+
+		for result := range jobOut {
+			out <- result
+		}
+		drainChan(jobIn)
+		return <-jobErr
+	}
+}
+
+func runJob(j job, in, out chan interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	j(in, out)
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStageSigner(t *testing.T) {
+	signer := Chain(Chain(Stage[string, string](SingleHashStage), MultiHashStage), CombineResultsStage)
+
+	results, err := RunStage(context.Background(), signer, []string{"0", "1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "29568666068035183841425683795340791879727309630931025356555_4958044192186797981418233587017209679042592862002427381542"
+	if len(results) != 1 || results[0] != expected {
+		t.Errorf("results not match\nGot: %v\nExpected: %v", results, expected)
+	}
+}
+
+func TestStageChainTypes(t *testing.T) {
+	double := Stage[int, int](func(ctx context.Context, in <-chan int, out chan<- int) error {
+		for value := range in {
+			out <- value * 2
+		}
+		return nil
+	})
+	format := Stage[int, string](func(ctx context.Context, in <-chan int, out chan<- string) error {
+		for value := range in {
+			out <- strconv.Itoa(value)
+		}
+		return nil
+	})
+
+	results, err := RunStage(context.Background(), Chain(double, format), []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(results, ",") != "2,4,6" {
+		t.Errorf("results not match: %v", results)
+	}
+}
+
+func TestStageChainError(t *testing.T) {
+	errBad := errors.New("bad value")
+	check := Stage[int, int](func(ctx context.Context, in <-chan int, out chan<- int) error {
+		for value := range in {
+			if value < 0 {
+				return errBad
+			}
+			out <- value
+		}
+		return nil
+	})
+	boom := Stage[int, int](func(ctx context.Context, in <-chan int, out chan<- int) error {
+		for value := range in {
+			out <- 10 / value
+		}
+		return nil
+	})
+
+	_, err := RunStage(context.Background(), Chain(check, boom), []int{1, 2, -1, 4})
+	if !errors.Is(err, errBad) {
+		t.Errorf("expected errBad, got %v", err)
+	}
+
+	_, err = RunStage(context.Background(), Chain(check, boom), []int{1, 0, 2})
+	if err == nil || !strings.Contains(err.Error(), "panic") {
+		t.Errorf("expected recovered panic, got %v", err)
+	}
+}
+
+func TestStageAdapters(t *testing.T) {
+	err := ExecutePipelineContext(context.Background(),
+		withContext(func(in, out chan interface{}) {
+			out <- 1
+			out <- 2.5
+		}),
+		stageContextJob(Stage[string, string](func(ctx context.Context, in <-chan string, out chan<- string) error {
+			for range in {
+			}
+			return nil
+		}), dataToString),
+	)
+	if err == nil || !strings.Contains(err.Error(), "unexpected data 2.5") {
+		t.Errorf("expected conversion error, got %v", err)
+	}
+
+	legacy := JobStage(func(in, out chan interface{}) {
+		for data := range in {
+			out <- data.(int) + 1
+		}
+	})
+	results, err := RunStage(context.Background(), legacy, []interface{}{1, 2})
+	if err != nil || len(results) != 2 || results[0] != 2 || results[1] != 3 {
+		t.Errorf("wrong legacy results: %v %v", results, err)
+	}
+}
+
+func TestStageContextJobEndlessSource(t *testing.T) {
+	errBad := errors.New("bad value")
+	for _, workers := range []int{0, 2} {
+		var calls int32
+		stage := Parallel(workers, func(ctx context.Context, value string) (string, error) {
+			atomic.AddInt32(&calls, 1)
+			if value == "3" {
+				return "", errBad
+			}
+			return value, nil
+		})
+
+		done := make(chan error, 1)
+		go func() {
+			done <- ExecutePipelineContext(context.Background(),
+				func(ctx context.Context, in, out chan interface{}) error {
+					for i := 0; ; i++ {
+						if err := sendContext(ctx, out, i); err != nil {
+							return err
+						}
+					}
+				},
+				stageContextJob(stage, dataToString),
+			)
+		}()
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, errBad) {
+				t.Errorf("workers %d: expected errBad, got %v", workers, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("workers %d: stage with endless source did not stop", workers)
+		}
+		// после ошибки fn не должна вызываться на всё подряд
+		if n := atomic.LoadInt32(&calls); n > 1000 {
+			t.Errorf("workers %d: fn kept running after failure: %d calls", workers, n)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -20,6 +21,27 @@ import (
 // PASS
 // ok      coursera/Week_3/hw3_bench       3.825s
 
+// Потоковая версия: один проход по файлу, один User, вывод через bufio.Writer
+// (easyjson, go test -bench . -benchmem, медиана из 5 запусков)
+// было:
+// BenchmarkFast                529           2326611 ns/op         2383104 B/op      13493 allocs/op
+// стало:
+// BenchmarkSlow                 48          25124257 ns/op        17897242 B/op     177390 allocs/op
+// BenchmarkFast                724           1705249 ns/op          532833 B/op      10096 allocs/op
+// map вместо линейного поиска по seenBrowsers (на этих данных браузеров
+// немного, так что по времени почти без разницы):
+// BenchmarkFast                764           1690398 ns/op          541673 B/op      10101 allocs/op
+// через Search: дерево запроса и шаблоны через замыкания заметно медленнее,
+// поэтому запрос SlowSearch идёт по отдельному пути (runAndroidMSIE), а общий
+// движок - для остальных запросов. Машина шумная, так что три версии
+// запускались попеременно, медиана из 20 запусков по 800 итераций:
+// map, до Search:
+// BenchmarkFast                800           2039890 ns/op          541673 B/op      10101 allocs/op
+// Search, общий движок:
+// BenchmarkFast                800           2205560 ns/op          541776 B/op      10104 allocs/op
+// Search, runAndroidMSIE:
+// BenchmarkFast                800           2127751 ns/op          541608 B/op      10101 allocs/op
+
 /*
    go test -bench . -benchmem -cpuprofile=cpu.out -memprofile=mem.out -memprofilerate=1 Тестим производительность и сохраняем рузультат
    go tool pprof --web cpu.out -> смотрим результаты как svg построенный graphviz в браузере
@@ -31,73 +53,81 @@ func FastSearch(out io.Writer) {
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	users := make([]User, 0)
-	for scanner.Scan() {
-		user := User{}
-		_ = user.UnmarshalJSON([]byte(scanner.Text()))
-		// fmt.Printf("%v %v\n", err, user)
-		users = append(users, user)
-	}
-
-	if err := scanner.Err(); err != nil {
+	if err := androidMSIESearch.Run(file, out); err != nil {
 		panic(err)
 	}
+}
 
-	seenBrowsers := []string{}
-
-	uniqueBrowsers := 0
-	foundUsers := ""
-
-	for i, user := range users {
+// androidMSIEQuery - запрос SlowSearch: пользователи и с Android, и с MSIE
+const androidMSIEQuery = `browsers contains "Android" and browsers contains "MSIE"`
+
+// androidMSIESearch с выводом по умолчанию NewSearch запускает через
+// runAndroidMSIE, а не через дерево запроса и шаблоны
+var androidMSIESearch = MustSearch(androidMSIEQuery, DefaultOutput)
+
+// runAndroidMSIE - androidMSIESearch без общего движка: две проверки на
+// подстроку и вывод сразу в bufio.Writer
+func runAndroidMSIE(r io.Reader, out io.Writer) error {
+	w := bufio.NewWriter(out)
+	w.WriteString(DefaultOutput.Header)
+
+	// уникальные браузеры с Android или MSIE
+	seenBrowsers := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(r)
+	// один User на весь файл: слайс браузеров переиспользуется между строками
+	user := &User{}
+	for i := 0; scanner.Scan(); i++ {
+		*user = User{Browsers: user.Browsers[:0]}
+		if err := user.UnmarshalJSON(scanner.Bytes()); err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
 
+		// один проход по браузерам: оба признака и учёт уникальных сразу
 		isAndroid := false
 		isMSIE := false
-
-		browsers := user.Browsers
-
-		for _, browserRaw := range browsers {
-			browser := browserRaw
-			if strings.Contains(browser, "Android") {
-				isAndroid = true
-				notSeenBefore := true
-				for _, item := range seenBrowsers {
-					if item == browser {
-						notSeenBefore = false
-					}
-				}
-				if notSeenBefore {
-					seenBrowsers = append(seenBrowsers, browser)
-					uniqueBrowsers++
-				}
-			}
-
-		}
-
-		for _, browserRaw := range browsers {
-			browser := browserRaw
-			if strings.Contains(browser, "MSIE") {
-				isMSIE = true
-				notSeenBefore := true
-				for _, item := range seenBrowsers {
-					if item == browser {
-						notSeenBefore = false
-					}
-				}
-				if notSeenBefore {
-					seenBrowsers = append(seenBrowsers, browser)
-					uniqueBrowsers++
-				}
+		for _, browser := range user.Browsers {
+			android := strings.Contains(browser, "Android")
+			msie := strings.Contains(browser, "MSIE")
+			if !android && !msie {
+				continue
 			}
+			isAndroid = isAndroid || android
+			isMSIE = isMSIE || msie
+			seenBrowsers[browser] = struct{}{}
 		}
 
 		if !(isAndroid && isMSIE) {
 			continue
 		}
-		email := strings.Replace(user.Email, "@", " [at] ", 1)
-		foundUsers += fmt.Sprintf("[%d] %s <%s>\n", i, user.Name, email)
+		writeUser(w, i, user)
 	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	var num [20]byte
+	w.WriteString("\nTotal unique browsers ")
+	w.Write(strconv.AppendInt(num[:0], int64(len(seenBrowsers)), 10))
+	w.WriteString("\n")
+	return w.Flush()
+}
 
-	fmt.Fprintln(out, "found users:\n"+foundUsers)
-	fmt.Fprintln(out, "Total unique browsers", len(seenBrowsers))
+// writeUser пишет "[i] name <email>" с заменой @ на " [at] " без
+// промежуточных строк
+func writeUser(w *bufio.Writer, i int, user *User) {
+	var num [20]byte
+	w.WriteByte('[')
+	w.Write(strconv.AppendInt(num[:0], int64(i), 10))
+	w.WriteString("] ")
+	w.WriteString(user.Name)
+	w.WriteString(" <")
+	if at := strings.IndexByte(user.Email, '@'); at >= 0 {
+		w.WriteString(user.Email[:at])
+		w.WriteString(" [at] ")
+		w.WriteString(user.Email[at+1:])
+	} else {
+		w.WriteString(user.Email)
+	}
+	w.WriteString(">\n")
 }
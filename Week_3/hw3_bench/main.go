@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// escapes позволяет писать переводы строк в шаблонах из командной строки
+var escapes = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\\`, `\`)
+
+// go run . -query 'country = "Russia"' [-row ...] [-header ...] [-footer ...] [-file ...]
+func main() {
+	query := flag.String("query", androidMSIESearch.query.String(), "query, see query.go")
+	header := flag.String("header", DefaultOutput.Header, "text printed before results")
+	row := flag.String("row", DefaultOutput.Row, "template for every found user")
+	footer := flag.String("footer", DefaultOutput.Footer, "template printed after results")
+	path := flag.String("file", filePath, "users file, one JSON object per line")
+	flag.Parse()
+
+	search, err := NewSearch(*query, OutputTemplate{
+		Header: escapes.Replace(*header),
+		Row:    escapes.Replace(*row),
+		Footer: escapes.Replace(*footer),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	if err := search.Run(file, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OutputTemplate задаёт вывод поиска: Header печатается в начале, Row - на
+// каждого найденного пользователя, Footer - в конце. В Row доступны
+// {index} (номер строки в файле), {name}, {email}, {email_at} (email с
+// " [at] " вместо @), {country}, {company}, {job}, {phone} и {browsers}
+// (через ", "); в Footer - {found}, {total} и {unique_browsers}. Header
+// печатается как есть. {{ и }} - литеральные скобки
+type OutputTemplate struct {
+	Header string
+	Row    string
+	Footer string
+}
+
+// DefaultOutput - вывод SlowSearch
+var DefaultOutput = OutputTemplate{
+	Header: "found users:\n",
+	Row:    "[{index}] {name} <{email_at}>\n",
+	Footer: "\nTotal unique browsers {unique_browsers}\n",
+}
+
+// searchStats - значения для Footer
+type searchStats struct {
+	found, total, uniqueBrowsers int
+}
+
+// templatePart - кусок шаблона: литерал или функция, пишущая поле
+type templatePart[T any] struct {
+	literal string
+	write   func(w *bufio.Writer, value T)
+}
+
+type compiledTemplate[T any] []templatePart[T]
+
+func (t compiledTemplate[T]) execute(w *bufio.Writer, value T) {
+	for _, part := range t {
+		if part.write != nil {
+			part.write(w, value)
+			continue
+		}
+		w.WriteString(part.literal)
+	}
+}
+
+// userRow - пользователь вместе с номером строки
+type userRow struct {
+	index int
+	user  *User
+}
+
+var rowFields = map[string]func(w *bufio.Writer, row userRow){
+	"index": func(w *bufio.Writer, row userRow) {
+		var num [20]byte
+		w.Write(strconv.AppendInt(num[:0], int64(row.index), 10))
+	},
+	"name":    func(w *bufio.Writer, row userRow) { w.WriteString(row.user.Name) },
+	"email":   func(w *bufio.Writer, row userRow) { w.WriteString(row.user.Email) },
+	"country": func(w *bufio.Writer, row userRow) { w.WriteString(row.user.Country) },
+	"company": func(w *bufio.Writer, row userRow) { w.WriteString(row.user.Company) },
+	"job":     func(w *bufio.Writer, row userRow) { w.WriteString(row.user.Job) },
+	"phone":   func(w *bufio.Writer, row userRow) { w.WriteString(row.user.Phone) },
+	// как strings.Replace(email, "@", " [at] ", 1), но без новой строки
+	"email_at": func(w *bufio.Writer, row userRow) {
+		email := row.user.Email
+		if at := strings.IndexByte(email, '@'); at >= 0 {
+			w.WriteString(email[:at])
+			w.WriteString(" [at] ")
+			email = email[at+1:]
+		}
+		w.WriteString(email)
+	},
+	"browsers": func(w *bufio.Writer, row userRow) {
+		for i, browser := range row.user.Browsers {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			w.WriteString(browser)
+		}
+	},
+}
+
+var footerFields = map[string]func(w *bufio.Writer, stats searchStats){
+	"found":           func(w *bufio.Writer, stats searchStats) { w.WriteString(strconv.Itoa(stats.found)) },
+	"total":           func(w *bufio.Writer, stats searchStats) { w.WriteString(strconv.Itoa(stats.total)) },
+	"unique_browsers": func(w *bufio.Writer, stats searchStats) { w.WriteString(strconv.Itoa(stats.uniqueBrowsers)) },
+}
+
+// compileTemplate разбирает шаблон с полями fields
+func compileTemplate[T any](src string, fields map[string]func(w *bufio.Writer, value T)) (compiledTemplate[T], error) {
+	var parts compiledTemplate[T]
+	literal := &strings.Builder{}
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, templatePart[T]{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case (c == '{' || c == '}') && i+1 < len(src) && src[i+1] == c:
+			literal.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(src[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("template: position %d: unterminated {", i)
+			}
+			name := src[i+1 : i+end]
+			write, ok := fields[name]
+			if !ok {
+				return nil, fmt.Errorf("template: position %d: unknown field {%s}", i, name)
+			}
+			flush()
+			parts = append(parts, templatePart[T]{write: write})
+			i += end
+		case c == '}':
+			return nil, fmt.Errorf("template: position %d: unexpected }", i)
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+	return parts, nil
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Язык запросов по пользователям:
+//
+//	browsers contains "Android" and browsers contains "MSIE" and country = "Russia"
+//
+// Условие - поле, оператор и строка в кавычках. Операторы: = (равно),
+// != (не равно), contains (подстрока). Для browsers условие выполнено, если
+// подходит хотя бы один браузер; != для него нет - вместо этого есть not.
+// Условия объединяются and, or, not и скобками; and связывает сильнее or
+
+// userFields - строковые поля User, доступные в запросах
+var userFields = map[string]func(*User) string{
+	"name":    func(u *User) string { return u.Name },
+	"email":   func(u *User) string { return u.Email },
+	"country": func(u *User) string { return u.Country },
+	"company": func(u *User) string { return u.Company },
+	"job":     func(u *User) string { return u.Job },
+	"phone":   func(u *User) string { return u.Phone },
+}
+
+// Query - скомпилированный запрос
+type Query struct {
+	source string
+	root   queryNode
+	// условия на browsers считаются отдельно, за один проход по браузерам
+	browserConds []browserCond
+}
+
+type browserCond struct {
+	op    string
+	value string
+}
+
+func (c browserCond) match(browser string) bool {
+	if c.op == "=" {
+		return browser == c.value
+	}
+	return strings.Contains(browser, c.value)
+}
+
+// queryNode - узел дерева запроса; browsers[i] - подошёл ли хоть один
+// браузер под i-е условие на browsers
+type queryNode interface {
+	eval(user *User, browsers []bool) bool
+}
+
+type (
+	andNode   struct{ left, right queryNode }
+	orNode    struct{ left, right queryNode }
+	notNode   struct{ node queryNode }
+	fieldNode struct {
+		field func(*User) string
+		op    string
+		value string
+	}
+	browsersNode struct{ cond int }
+)
+
+func (n andNode) eval(u *User, b []bool) bool { return n.left.eval(u, b) && n.right.eval(u, b) }
+func (n orNode) eval(u *User, b []bool) bool  { return n.left.eval(u, b) || n.right.eval(u, b) }
+func (n notNode) eval(u *User, b []bool) bool { return !n.node.eval(u, b) }
+
+func (n browsersNode) eval(u *User, b []bool) bool { return b[n.cond] }
+
+func (n fieldNode) eval(u *User, b []bool) bool {
+	value := n.field(u)
+	switch n.op {
+	case "=":
+		return value == n.value
+	case "!=":
+		return value != n.value
+	}
+	return strings.Contains(value, n.value)
+}
+
+// CompileQuery разбирает запрос src
+func CompileQuery(src string) (*Query, error) {
+	tokens, err := lexQuery(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens, query: &Query{source: src}}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+
+	p.query.root = root
+	return p.query, nil
+}
+
+// MustCompileQuery - CompileQuery для запросов, известных при компиляции программы
+func MustCompileQuery(src string) *Query {
+	q, err := CompileQuery(src)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func (q *Query) String() string {
+	return q.source
+}
+
+// match проверяет пользователя. matched - буфер на len(browserConds)
+// значений, чтобы не выделять его на каждого пользователя; seen, если не nil,
+// получает браузеры, подошедшие хоть под одно условие на browsers
+func (q *Query) match(user *User, matched []bool, seen map[string]struct{}) bool {
+	for i := range matched {
+		matched[i] = false
+	}
+	for _, browser := range user.Browsers {
+		hit := false
+		for i, cond := range q.browserConds {
+			if cond.match(browser) {
+				matched[i] = true
+				hit = true
+			}
+		}
+		if hit && seen != nil {
+			seen[browser] = struct{}{}
+		}
+	}
+	return q.root.eval(user, matched)
+}
+
+// Match проверяет, подходит ли пользователь под запрос
+func (q *Query) Match(user *User) bool {
+	return q.match(user, make([]bool, len(q.browserConds)), nil)
+}
+
+const (
+	tokEOF = iota
+	tokIdent
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type queryToken struct {
+	kind  int
+	text  string
+	pos   int
+	value string // для строк - значение без кавычек
+}
+
+func (t queryToken) String() string {
+	if t.kind == tokEOF {
+		return "end of query"
+	}
+	return strconv.Quote(t.text)
+}
+
+func lexQuery(src string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')':
+			kind := tokLParen
+			if c == ')' {
+				kind = tokRParen
+			}
+			tokens = append(tokens, queryToken{kind: kind, text: src[i : i+1], pos: i})
+			i++
+		case c == '=':
+			tokens = append(tokens, queryToken{kind: tokOp, text: "=", pos: i})
+			i++
+		case c == '!' && i+1 < len(src) && src[i+1] == '=':
+			tokens = append(tokens, queryToken{kind: tokOp, text: "!=", pos: i})
+			i += 2
+		case c == '"':
+			end := i + 1
+			for ; end < len(src) && src[end] != '"'; end++ {
+				if src[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("query: position %d: unterminated string", i)
+			}
+			value, err := strconv.Unquote(src[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("query: position %d: bad string %s", i, src[i:end+1])
+			}
+			tokens = append(tokens, queryToken{kind: tokString, text: src[i : end+1], pos: i, value: value})
+			i = end + 1
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			end := i
+			for end < len(src) && (src[end] == '_' || src[end] >= 'a' && src[end] <= 'z' || src[end] >= 'A' && src[end] <= 'Z') {
+				end++
+			}
+			tokens = append(tokens, queryToken{kind: tokIdent, text: strings.ToLower(src[i:end]), pos: i})
+			i = end
+		default:
+			return nil, fmt.Errorf("query: position %d: unexpected %q", i, c)
+		}
+	}
+	return append(tokens, queryToken{kind: tokEOF, pos: len(src)}), nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	query  *Query
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) keyword(word string) bool {
+	if tok := p.peek(); tok.kind == tokIdent && tok.text == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) errorf(tok queryToken, format string, args ...interface{}) error {
+	return fmt.Errorf("query: position %d: %s", tok.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *queryParser) or() (queryNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) and() (queryNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) unary() (queryNode, error) {
+	if p.keyword("not") {
+		node, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+
+	if tok := p.peek(); tok.kind == tokLParen {
+		p.next()
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokRParen {
+			return nil, p.errorf(tok, "expected \")\", got %s", tok)
+		}
+		return node, nil
+	}
+
+	return p.condition()
+}
+
+func (p *queryParser) condition() (queryNode, error) {
+	fieldTok := p.next()
+	if fieldTok.kind != tokIdent {
+		return nil, p.errorf(fieldTok, "expected field, got %s", fieldTok)
+	}
+
+	opTok := p.next()
+	op := opTok.text
+	if opTok.kind != tokOp && !(opTok.kind == tokIdent && op == "contains") {
+		return nil, p.errorf(opTok, "expected =, != or contains, got %s", opTok)
+	}
+
+	valueTok := p.next()
+	if valueTok.kind != tokString {
+		return nil, p.errorf(valueTok, "expected quoted string, got %s", valueTok)
+	}
+
+	if fieldTok.text == "browsers" {
+		if op == "!=" {
+			return nil, p.errorf(opTok, "!= is not supported for browsers, use not")
+		}
+		p.query.browserConds = append(p.query.browserConds, browserCond{op: op, value: valueTok.value})
+		return browsersNode{cond: len(p.query.browserConds) - 1}, nil
+	}
+
+	field, ok := userFields[fieldTok.text]
+	if !ok {
+		return nil, p.errorf(fieldTok, "unknown field %s", fieldTok)
+	}
+	return fieldNode{field: field, op: op, value: valueTok.value}, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestQueryMatch(t *testing.T) {
+	user := &User{
+		Name:     "Sharon Crawford",
+		Country:  "Russia",
+		Email:    "sharon@example.com",
+		Browsers: []string{"Mozilla/5.0 (Android; Linux armv7l)", "Mozilla/4.0 (compatible; MSIE 7.0)"},
+	}
+
+	cases := []struct {
+		query    string
+		expected bool
+	}{
+		{`browsers contains "Android" and browsers contains "MSIE"`, true},
+		{`browsers contains "Android" and browsers contains "MSIE" and country = "Russia"`, true},
+		{`browsers contains "Android" and country != "Russia"`, false},
+		{`country = "Chile" or name contains "Sharon"`, true},
+		{`not browsers contains "Opera"`, true},
+		{`browsers = "Mozilla/4.0 (compatible; MSIE 7.0)"`, true},
+		{`country = "Chile" and (name contains "Sharon" or country = "Russia")`, false},
+		// and связывает сильнее or
+		{`country = "Russia" or name = "x" and email = "y"`, true},
+		{`NOT (email contains "@example.com")`, false},
+	}
+
+	for _, c := range cases {
+		q, err := CompileQuery(c.query)
+		if err != nil {
+			t.Errorf("query %s: unexpected error: %v", c.query, err)
+			continue
+		}
+		if got := q.Match(user); got != c.expected {
+			t.Errorf("query %s: got %v, expected %v", c.query, got, c.expected)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	cases := []struct {
+		query string
+		err   string
+	}{
+		{`age = "1"`, `position 0: unknown field "age"`},
+		{`country "Russia"`, `position 8: expected =, != or contains`},
+		{`country = Russia`, `position 10: expected quoted string`},
+		{`country = "Russia`, "unterminated string"},
+		{`browsers != "MSIE"`, "use not"},
+		{`(country = "Russia"`, `expected ")", got end of query`},
+		{`country = "Russia" country = "Chile"`, `position 19: unexpected "country"`},
+		{`country ~ "R"`, `position 8: unexpected '~'`},
+		{``, "expected field, got end of query"},
+	}
+
+	for _, c := range cases {
+		if _, err := CompileQuery(c.query); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("query %s: expected error with %q, got %v", c.query, c.err, err)
+		}
+	}
+}
+
+func TestSearchTemplate(t *testing.T) {
+	users := strings.Join([]string{
+		`{"browsers":["Opera/9.80 (Android)","MSIE 8.0"],"country":"Russia","email":"a@b.ru","name":"Anna"}`,
+		`{"browsers":["Android 4","Chrome"],"country":"Russia","email":"c@d.ru","name":"Boris"}`,
+		`{"browsers":["Android 4"],"country":"Chile","email":"e@f.cl","name":"Carlos"}`,
+	}, "\n")
+
+	search, err := NewSearch(`country = "Russia" and browsers contains "Android"`, OutputTemplate{
+		Header: "# users\n",
+		Row:    "{index}: {name} {{{email_at}}} [{browsers}]\n",
+		Footer: "{found} of {total}, {unique_browsers} browsers\n",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := search.Run(strings.NewReader(users), out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// браузеры считаются и у тех, кто под запрос не подошёл
+	expected := "# users\n" +
+		"0: Anna {a [at] b.ru} [Opera/9.80 (Android), MSIE 8.0]\n" +
+		"1: Boris {c [at] d.ru} [Android 4, Chrome]\n" +
+		"2 of 3, 2 browsers\n"
+	if out.String() != expected {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), expected)
+	}
+}
+
+func TestSearchTemplateErrors(t *testing.T) {
+	cases := []struct {
+		output OutputTemplate
+		err    string
+	}{
+		{OutputTemplate{Row: "{age}"}, "row template: position 0: unknown field {age}"},
+		{OutputTemplate{Row: "{name"}, "unterminated {"},
+		{OutputTemplate{Row: "name}"}, "unexpected }"},
+		{OutputTemplate{Footer: "{name}"}, "footer template: position 0: unknown field {name}"},
+	}
+
+	for _, c := range cases {
+		if _, err := NewSearch(`name = "x"`, c.output); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("template %+v: expected error with %q, got %v", c.output, c.err, err)
+		}
+	}
+}
+
+func TestSearchAndroidMSIEGeneric(t *testing.T) {
+	if androidMSIESearch.run == nil {
+		t.Fatalf("predefined search does not use the fast path")
+	}
+
+	// тот же запрос в другом порядке идёт через общий движок
+	generic := MustSearch(`browsers contains "MSIE" and browsers contains "Android"`, DefaultOutput)
+	if generic.run != nil {
+		t.Fatalf("generic search uses the fast path")
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	genericOut := &bytes.Buffer{}
+	if err := generic.Run(file, genericOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fastOut := &bytes.Buffer{}
+	FastSearch(fastOut)
+
+	if fastOut.String() != genericOut.String() {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", fastOut.String(), genericOut.String())
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Search - запрос вместе с шаблоном вывода, собранные один раз
+type Search struct {
+	query  *Query
+	header string
+	row    compiledTemplate[userRow]
+	footer compiledTemplate[searchStats]
+	// run, если задан, выполняет поиск вместо общего движка
+	run func(r io.Reader, out io.Writer) error
+}
+
+// NewSearch компилирует запрос и шаблон вывода
+func NewSearch(query string, output OutputTemplate) (*Search, error) {
+	q, err := CompileQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	row, err := compileTemplate(output.Row, rowFields)
+	if err != nil {
+		return nil, fmt.Errorf("row %w", err)
+	}
+	footer, err := compileTemplate(output.Footer, footerFields)
+	if err != nil {
+		return nil, fmt.Errorf("footer %w", err)
+	}
+	s := &Search{query: q, header: output.Header, row: row, footer: footer}
+	// поиск SlowSearch идёт по отдельному быстрому пути, см. fast.go
+	if query == androidMSIEQuery && output == DefaultOutput {
+		s.run = runAndroidMSIE
+	}
+	return s, nil
+}
+
+// MustSearch - NewSearch для поисков, известных при компиляции программы
+func MustSearch(query string, output OutputTemplate) *Search {
+	s, err := NewSearch(query, output)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Run читает пользователей из r по одному JSON в строке и пишет в out
+// найденных. Файл проходится один раз, User переиспользуется, а уникальными
+// считаются браузеры, подошедшие под любое условие на browsers
+func (s *Search) Run(r io.Reader, out io.Writer) error {
+	if s.run != nil {
+		return s.run(r, out)
+	}
+
+	w := bufio.NewWriter(out)
+	w.WriteString(s.header)
+
+	seenBrowsers := make(map[string]struct{})
+	matched := make([]bool, len(s.query.browserConds))
+	stats := searchStats{}
+
+	scanner := bufio.NewScanner(r)
+	// один User на весь файл: слайс браузеров переиспользуется между строками
+	user := &User{}
+	for ; scanner.Scan(); stats.total++ {
+		*user = User{Browsers: user.Browsers[:0]}
+		if err := user.UnmarshalJSON(scanner.Bytes()); err != nil {
+			return fmt.Errorf("line %d: %w", stats.total+1, err)
+		}
+
+		if !s.query.match(user, matched, seenBrowsers) {
+			continue
+		}
+		stats.found++
+		s.row.execute(w, userRow{index: stats.total, user: user})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	stats.uniqueBrowsers = len(seenBrowsers)
+	s.footer.execute(w, stats)
+	return w.Flush()
+}